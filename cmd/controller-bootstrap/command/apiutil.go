@@ -14,27 +14,38 @@
 package command
 
 import (
-	"context"
 	"fmt"
 	"github.com/gertd/go-pluralize"
-	"gopkg.in/src-d/go-git.v4"
+	"io/ioutil"
 	"os"
-	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
-	"syscall"
 	"time"
 
 	awssdkmodel "github.com/aws/aws-sdk-go/private/model/api"
 )
 
 type metaVars struct {
-	ServiceID           string
-	ServicePackageName  string
-	ServiceModelName    string
-	ServiceAbbreviation string
-	ServiceFullName     string
-	CRDNames            []string
+	ServiceID           string `json:"serviceID"`
+	ServicePackageName  string `json:"servicePackageName"`
+	ServiceModelName    string `json:"serviceModelName"`
+	ServiceAbbreviation string `json:"serviceAbbreviation"`
+	ServiceFullName     string `json:"serviceFullName"`
+	// ServiceDocumentationURL is the URL of the service's API reference
+	ServiceDocumentationURL string `json:"serviceDocumentationURL"`
+	// ServiceDocumentation is the first paragraph of the service model's
+	// documentation as plain text
+	ServiceDocumentation string   `json:"serviceDocumentation"`
+	CRDNames             []string `json:"crdNames"`
+	// BootstrapResources are the resources of other AWS services the e2e
+	// tests of the selected custom resources depend on
+	BootstrapResources []*bootstrapResource `json:"bootstrapResources"`
+	// ReplacementValues are the values of the placeholders of the e2e test
+	// resource manifests
+	ReplacementValues []*replacementValue `json:"replacementValues"`
+	// crdModels are the models of the custom resources, keyed by name
+	crdModels map[string]*crdModel
 }
 
 const (
@@ -42,63 +53,56 @@ const (
 	defaultGitCloneTimeout = 180 * time.Second
 )
 
+// pluralizer is the pluralize client shared by the resource name inference
+// and the template functions
+var pluralizer = pluralize.NewClient()
+
+// sdkDir is the path to the local clone of the aws-sdk-go repository,
+// set by ensureSDKRepo
+var sdkDir string
+
 // AWSSDKHelper is a helper struct for aws-sdk-go model API loader
 type AWSSDKHelper struct {
 	loader *awssdkmodel.Loader
+	// Default is set by `FirstAPIVersion`
+	apiVersion string
 }
 
-// getServiceResources infers aws-sdk-go to fetch the service metadata and custom resource names
-func getServiceResources() (*metaVars, error) {
-	hd, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Printf("unable to determine $HOME: %s\n", err)
-		os.Exit(1)
-	}
-	cacheACKDir := filepath.Join(hd, ".cache", "aws-controllers-k8s")
-	ctx, cancel := contextWithSigterm(context.Background())
-	defer cancel()
-	if err = ensureSDKRepo(ctx, cacheACKDir); err != nil {
-		return nil, err
-	}
-
-	modelPath, err := findModelPath()
-	if err != nil {
-		return nil, fmt.Errorf("unable to find the supplied service's API file, please re-try after specifying the service model name")
-	}
-
+// getServiceResources infers aws-sdk-go to fetch the service metadata and custom resource names,
+// along with the API version of the service model they were inferred from
+func getServiceResources() (*metaVars, string, error) {
 	h := newAWSSDKHelper()
-	svcVars, err := h.API(modelPath)
+	svcVars, err := h.API()
 	if err != nil {
-		return nil, err
+		return nil, "", fmt.Errorf("unable to find the supplied service's API file, please re-try specifying the service model name")
 	}
-	return svcVars, nil
+	if svcVars.CRDNames, err = selectResources(svcVars.CRDNames, optResources); err != nil {
+		return nil, "", err
+	}
+	placeholders := e2ePlaceholders(svcVars.crdModels, svcVars.CRDNames, optOutputPath)
+	svcVars.BootstrapResources = getBootstrapResources(optServiceAlias, placeholders)
+	svcVars.ReplacementValues = getReplacementValues(optServiceAlias, placeholders)
+	return svcVars, h.apiVersion, nil
 }
 
-// findModelPath returns path to the supplied service's API file
-func findModelPath() (string, error) {
-	serviceModelName := strings.ToLower(optModelName)
-	if optModelName == "" {
-		serviceModelName = strings.ToLower(optServiceAlias)
+// selectResources returns the custom resource names which were selected,
+// or all of them when no selection was made
+func selectResources(crdNames []string, selected []string) ([]string, error) {
+	if len(selected) == 0 {
+		return crdNames, nil
 	}
-	apiPath := filepath.Join(sdkDir, "models", "apis", serviceModelName)
-	apiFile := ""
-	err := filepath.Walk(apiPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && info.Name() == "api-2.json" {
-			_, err = os.Open(path)
-			if err != nil {
-				return err
-			}
-			apiFile = path
+	known := map[string]bool{}
+	for _, name := range crdNames {
+		known[name] = true
+	}
+	var names []string
+	for _, name := range selected {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown resource %q, expected one of %s", name, strings.Join(crdNames, ", "))
 		}
-		return nil
-	})
-	if err != nil {
-		return "", err
+		names = append(names, name)
 	}
-	return apiFile, nil
+	return names, nil
 }
 
 // newAWSSDKHelper returns a new AWSSDKHelper struct
@@ -113,7 +117,16 @@ func newAWSSDKHelper() *AWSSDKHelper {
 
 // API returns the populated metaVars struct with the service metadata
 // and custom resource names extracted from the aws-sdk-go model API object
-func (h *AWSSDKHelper) API(modelPath string) (*metaVars, error) {
+func (h *AWSSDKHelper) API() (*metaVars, error) {
+	serviceModelName := strings.ToLower(optModelName)
+	if optModelName == "" {
+		serviceModelName = strings.ToLower(optServiceAlias)
+	}
+	modelPath, err := h.findModelPath(serviceModelName)
+	if err != nil {
+		return nil, err
+	}
+
 	// loads the API model file(s) and returns the map of API package
 	apis, err := h.loader.Load([]string{modelPath})
 	if err != nil {
@@ -129,96 +142,129 @@ func (h *AWSSDKHelper) API(modelPath string) (*metaVars, error) {
 	return nil, err
 }
 
+// findModelPath returns the path to the supplied service's API file
+func (h *AWSSDKHelper) findModelPath(
+	serviceModelName string,
+) (string, error) {
+	if h.apiVersion == "" {
+		apiVersion, err := h.firstAPIVersion(serviceModelName)
+		if err != nil {
+			return "", err
+		}
+		h.apiVersion = apiVersion
+	}
+	versionPath := filepath.Join(
+		sdkDir, "models", "apis", serviceModelName, h.apiVersion,
+	)
+	modelPath := filepath.Join(versionPath, "api-2.json")
+	return modelPath, nil
+}
+
+// FirstAPIVersion returns the first found API version for a service API.
+// (e.h. "2012-10-03")
+func (h *AWSSDKHelper) firstAPIVersion(serviceModelName string) (string, error) {
+	versions, err := h.getAPIVersions(serviceModelName)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(versions)
+	return versions[0], nil
+}
+
+// GetAPIVersions returns the list of API Versions found in a service directory.
+func (h *AWSSDKHelper) getAPIVersions(serviceModelName string) ([]string, error) {
+	apiPath := filepath.Join(sdkDir, "models", "apis", serviceModelName)
+	versionDirs, err := ioutil.ReadDir(apiPath)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, f := range versionDirs {
+		version := f.Name()
+		fp := filepath.Join(apiPath, version)
+		fi, err := os.Lstat(fp)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("found %s: %v", version, "expected to find only directories in api model directory but found non-directory")
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no valid version directories found")
+	}
+	return versions, nil
+}
+
 // serviceMetaVars returns a metaVars struct populated with metadata
 // and custom resource names for the supplied AWS service
 func serviceMetaVars(api *awssdkmodel.API) *metaVars {
+	crdNames := getCRDNames(api)
 	return &metaVars{
-		ServicePackageName:  strings.ToLower(optServiceAlias),
-		ServiceID:           api.Metadata.ServiceID,
-		ServiceModelName:    strings.ToLower(optModelName),
-		ServiceAbbreviation: api.Metadata.ServiceAbbreviation,
-		ServiceFullName:     api.Metadata.ServiceFullName,
-		CRDNames:            getCRDNames(api),
+		ServicePackageName:      strings.ToLower(optServiceAlias),
+		ServiceID:               api.Metadata.ServiceID,
+		ServiceModelName:        strings.ToLower(optModelName),
+		ServiceAbbreviation:     api.Metadata.ServiceAbbreviation,
+		ServiceFullName:         api.Metadata.ServiceFullName,
+		ServiceDocumentationURL: serviceDocumentationURL(api.Metadata.UID),
+		ServiceDocumentation:    serviceDocumentation(api.Documentation),
+		CRDNames:                crdNames,
+		crdModels:               getCRDModels(api, crdNames),
+	}
+}
+
+// serviceDocumentationURL returns the URL of the API reference of the
+// service model with the supplied unique ID, e.g. ecr-2015-09-21
+func serviceDocumentationURL(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	return "https://docs.aws.amazon.com/goto/WebAPI/" + uid
+}
+
+// defaultServiceLink returns the URL of the product page of the service with the
+// supplied model service ID, e.g. https://aws.amazon.com/api-gateway/ for
+// "API Gateway"
+func defaultServiceLink(serviceID string) string {
+	if serviceID == "" {
+		return ""
+	}
+	slug := strings.Join(strings.Fields(strings.ToLower(serviceID)), "-")
+	return "https://aws.amazon.com/" + slug + "/"
+}
+
+// serviceDocumentation returns the first paragraph of the supplied model
+// documentation, which aws-sdk-go renders as Go comment lines
+func serviceDocumentation(doc string) string {
+	var words []string
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		if line == "" {
+			if len(words) > 0 {
+				break
+			}
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
 	}
+	return strings.Join(words, " ")
 }
 
 // getCRDNames appends custom resource names with the prefix "Create" followed by a singular noun
 // to the slice, crdNames
 func getCRDNames(api *awssdkmodel.API) []string {
 	var crdNames []string
-	pluralize := pluralize.NewClient()
 	for _, opName := range api.OperationNames() {
 		if strings.HasPrefix(opName, "CreateBatch") {
 			continue
 		}
 		if strings.HasPrefix(opName, "Create") {
 			resName := strings.TrimPrefix(opName, "Create")
-			if pluralize.IsSingular(resName) {
+			if pluralizer.IsSingular(resName) {
 				crdNames = append(crdNames, resName)
 			}
 		}
 	}
 	return crdNames
 }
-
-// ensureSDKRepo ensures that we have a git clone'd copy of the aws-sdk-go
-// repository, which we use model JSON files from.
-func ensureSDKRepo(
-	ctx context.Context,
-	cacheDir string,
-) error {
-	var err error
-	srcPath := filepath.Join(cacheDir, "src")
-	if err = os.MkdirAll(srcPath, os.ModePerm); err != nil {
-		return err
-	}
-
-	// Clone repository if it doen't exist
-	sdkDir = filepath.Join(srcPath, "aws-sdk-go")
-
-	if _, err = os.Stat(sdkDir); os.IsNotExist(err) {
-
-		ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
-		defer cancel()
-		err = CloneRepository(ct, sdkDir, sdkRepoURL)
-		if err != nil {
-			return fmt.Errorf("canot clone repository: %v", err)
-		}
-	}
-	return err
-}
-
-// CloneRepository clones a git repository into a given directory.
-// Calling this function is equivalent to executing `git clone $repositoryURL $path`
-func CloneRepository(ctx context.Context, path, repositoryURL string) error {
-	_, err := git.PlainCloneContext(ctx, path, false, &git.CloneOptions{
-		URL:      repositoryURL,
-		Progress: nil,
-		// Clone and fetch all tags
-		Tags: git.AllTags,
-	})
-	return err
-}
-
-func contextWithSigterm(ctx context.Context) (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithCancel(ctx)
-	signalCh := make(chan os.Signal, 1)
-
-	// recreate the context.CancelFunc
-	cancelFunc := func() {
-		signal.Stop(signalCh)
-		cancel()
-	}
-
-	// notify on SIGINT or SIGTERM
-	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		select {
-		case <-signalCh:
-			cancel()
-		case <-ctx.Done():
-		}
-	}()
-
-	return ctx, cancelFunc
-}
@@ -0,0 +1,76 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"testing"
+)
+
+func TestServiceDocumentationURL(t *testing.T) {
+	tests := []struct {
+		uid  string
+		want string
+	}{
+		{"", ""},
+		{"ecr-2015-09-21", "https://docs.aws.amazon.com/goto/WebAPI/ecr-2015-09-21"},
+		{"states-2016-11-23", "https://docs.aws.amazon.com/goto/WebAPI/states-2016-11-23"},
+	}
+	for _, tt := range tests {
+		if got := serviceDocumentationURL(tt.uid); got != tt.want {
+			t.Errorf("serviceDocumentationURL(%q) = %q, want %q", tt.uid, got, tt.want)
+		}
+	}
+}
+
+func TestServiceDocumentation(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"empty", "", ""},
+		{
+			"single line",
+			"// Amazon Elastic Container Registry (Amazon ECR) is a managed container image registry service.",
+			"Amazon Elastic Container Registry (Amazon ECR) is a managed container image registry service.",
+		},
+		{
+			"first paragraph only",
+			"//\n// AWS Step Functions is a service that lets you coordinate\n//   the components of distributed applications.\n//\n// For more information, see the developer guide.\n",
+			"AWS Step Functions is a service that lets you coordinate the components of distributed applications.",
+		},
+	}
+	for _, tt := range tests {
+		if got := serviceDocumentation(tt.doc); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultServiceLink(t *testing.T) {
+	tests := []struct {
+		serviceID string
+		want      string
+	}{
+		{"", ""},
+		{"ECR", "https://aws.amazon.com/ecr/"},
+		{"DynamoDB", "https://aws.amazon.com/dynamodb/"},
+		{"API Gateway", "https://aws.amazon.com/api-gateway/"},
+	}
+	for _, tt := range tests {
+		if got := defaultServiceLink(tt.serviceID); got != tt.want {
+			t.Errorf("defaultServiceLink(%q) = %q, want %q", tt.serviceID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"strings"
+)
+
+// bootstrapResource is a resource of another AWS service the e2e tests of
+// the controller depend on, created with an acktest.bootstrapping type
+type bootstrapResource struct {
+	// Name is the field of the BootstrapResources dataclass, e.g. "ServiceRole"
+	Name string `json:"name"`
+	// Type is the acktest.bootstrapping type, e.g. "Role"
+	Type string `json:"type"`
+	// Module is the acktest.bootstrapping module of the Type, e.g. "iam"
+	Module string `json:"module"`
+}
+
+var (
+	bootstrapVPC    = &bootstrapResource{Name: "ServiceVPC", Type: "VPC", Module: "vpc"}
+	bootstrapRole   = &bootstrapResource{Name: "ServiceRole", Type: "Role", Module: "iam"}
+	bootstrapKey    = &bootstrapResource{Name: "ServiceKey", Type: "Key", Module: "kms"}
+	bootstrapBucket = &bootstrapResource{Name: "ServiceBucket", Type: "Bucket", Module: "s3"}
+)
+
+// bootstrapResourceOrder is the order in which the bootstrap resources
+// are declared
+var bootstrapResourceOrder = []*bootstrapResource{
+	bootstrapVPC,
+	bootstrapRole,
+	bootstrapKey,
+	bootstrapBucket,
+}
+
+// bootstrapReference is a shape member referring to an attribute of a
+// bootstrap resource
+type bootstrapReference struct {
+	resource *bootstrapResource
+	// attribute is the Python attribute of the resource, e.g. "arn"
+	attribute string
+}
+
+// findBootstrapReference returns the bootstrap resource the supplied shape
+// member refers to, or nil if it does not refer to another AWS service.
+// Buckets are not bootstrapped for the S3 controller itself.
+func findBootstrapReference(serviceAlias, memberName string) *bootstrapReference {
+	name := strings.ToLower(strings.Join(splitWords(memberName), ""))
+	switch {
+	case strings.HasSuffix(name, "vpcid"):
+		return &bootstrapReference{bootstrapVPC, "vpc_id"}
+	case strings.HasSuffix(name, "subnetid"):
+		return &bootstrapReference{bootstrapVPC, "public_subnets.subnet_ids[0]"}
+	case name == "role" || strings.HasSuffix(name, "rolearn"):
+		return &bootstrapReference{bootstrapRole, "arn"}
+	case strings.HasPrefix(name, "kms") && (strings.HasSuffix(name, "keyid") || strings.HasSuffix(name, "keyarn")):
+		return &bootstrapReference{bootstrapKey, "arn"}
+	case strings.EqualFold(serviceAlias, "s3"):
+		return nil
+	case strings.HasSuffix(name, "bucketname") || name == "s3bucket":
+		return &bootstrapReference{bootstrapBucket, "name"}
+	}
+	return nil
+}
+
+// getBootstrapResources returns the bootstrap resources referred to by the
+// supplied placeholders, see e2ePlaceholders
+func getBootstrapResources(serviceAlias string, placeholders map[string]string) []*bootstrapResource {
+	used := map[*bootstrapResource]bool{}
+	for _, memberName := range placeholders {
+		if ref := findBootstrapReference(serviceAlias, memberName); memberName != "" && ref != nil {
+			used[ref.resource] = true
+		}
+	}
+	resources := []*bootstrapResource{}
+	for _, res := range bootstrapResourceOrder {
+		if used[res] {
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
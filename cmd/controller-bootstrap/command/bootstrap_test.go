@@ -0,0 +1,176 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFindBootstrapReference(t *testing.T) {
+	cases := []struct {
+		service   string
+		member    string
+		resource  *bootstrapResource
+		attribute string
+	}{
+		{"eks", "VpcId", bootstrapVPC, "vpc_id"},
+		{"eks", "SubnetId", bootstrapVPC, "public_subnets.subnet_ids[0]"},
+		{"lambda", "Role", bootstrapRole, "arn"},
+		{"sagemaker", "ExecutionRoleArn", bootstrapRole, "arn"},
+		{"rds", "KMSKeyId", bootstrapKey, "arn"},
+		{"rds", "DatabaseInstallationFilesS3BucketName", bootstrapBucket, "name"},
+		{"s3", "BucketName", nil, ""},
+		{"ecr", "RepositoryName", nil, ""},
+		{"iam", "RoleName", nil, ""},
+	}
+	for _, c := range cases {
+		ref := findBootstrapReference(c.service, c.member)
+		if c.resource == nil {
+			if ref != nil {
+				t.Errorf("%s %s: expected no reference, got %s", c.service, c.member, ref.resource.Name)
+			}
+			continue
+		}
+		if ref == nil || ref.resource != c.resource || ref.attribute != c.attribute {
+			t.Errorf("%s %s: got %+v, want %s.%s", c.service, c.member, ref, c.resource.Name, c.attribute)
+		}
+	}
+}
+
+func TestGetBootstrapResources(t *testing.T) {
+	placeholders := map[string]string{
+		"ROLE":          "Role",
+		"FUNCTION_NAME": "FunctionName",
+		"SUBNET_ID":     "SubnetId",
+		"ROLE_ARN":      "RoleArn",
+		// a placeholder of a manifest in the output directory
+		"KMS_KEY_ARN": "KMSKEYARN",
+		"ALIAS_NAME":  "",
+	}
+	got := getBootstrapResources("lambda", placeholders)
+	if want := []*bootstrapResource{bootstrapVPC, bootstrapRole, bootstrapKey}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = getBootstrapResources("lambda", map[string]string{"FUNCTION_NAME": "FunctionName"})
+	if want := []*bootstrapResource{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected no bootstrap resources, got %v", got)
+	}
+}
+
+func TestBootstrapResourcesTemplates(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := fstest.MapFS{}
+	for _, path := range []string{
+		"_partials/header.py.tpl",
+		"test/e2e/bootstrap_resources.py.tpl",
+		"test/e2e/generated_bootstrap_resources.py.tpl",
+		"test/e2e/service_bootstrap.py.tpl",
+	} {
+		data, err := fs.ReadFile(base, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = &fstest.MapFile{Data: data}
+	}
+	pack := lintTestPack(t, files)
+	partials, err := pack.parsePartials()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		resources []*bootstrapResource
+		want      map[string][]string
+	}{
+		{
+			name:      "all",
+			resources: bootstrapResourceOrder,
+			want: map[string][]string{
+				"test/e2e/generated_bootstrap_resources.py": {
+					"from acktest.bootstrapping.vpc import VPC\n",
+					"from acktest.bootstrapping.iam import Role\n",
+					"from acktest.bootstrapping.kms import Key\n",
+					"from acktest.bootstrapping.s3 import Bucket\n",
+					"class GeneratedBootstrapResources(Resources):\n" +
+						"    ServiceVPC: VPC\n" +
+						"    ServiceRole: Role\n" +
+						"    ServiceKey: Key\n" +
+						"    ServiceBucket: Bucket\n",
+					"    return {\n" +
+						"        \"ServiceVPC\": VPC(name_prefix=\"ecr-vpc\", num_public_subnet=2, num_private_subnet=0),\n" +
+						"        \"ServiceRole\": Role(\"ecr-role\", principal_service=\"ecr.amazonaws.com\"),\n" +
+						"        \"ServiceKey\": Key(\"ecr-key\"),\n" +
+						"        \"ServiceBucket\": Bucket(\"ecr-bucket\"),\n" +
+						"    }\n",
+				},
+			},
+		},
+		{
+			name:      "empty",
+			resources: []*bootstrapResource{},
+			want: map[string][]string{
+				"test/e2e/generated_bootstrap_resources.py": {
+					"class GeneratedBootstrapResources(Resources):\n    pass\n",
+					"    \"\"\"\n    return {}\n",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tplVars := testTemplateVars()
+		tplVars.BootstrapResources = tt.resources
+		targets, err := renderTargets(pack, tplVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, _, err := renderFiles(context.Background(), partials, targets, t.TempDir(), 1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		contents := map[string]string{}
+		for _, f := range rendered {
+			contents[f.file] = string(f.contents)
+			wantMode := writeModeCreateOnly
+			if f.file == "test/e2e/generated_bootstrap_resources.py" {
+				wantMode = writeModeOverwrite
+			}
+			if f.mode != wantMode {
+				t.Errorf("%s: got mode %v for %s, want %v", tt.name, f.mode, f.file, wantMode)
+			}
+		}
+		// the user's files only extend the generated ones
+		tt.want["test/e2e/bootstrap_resources.py"] = []string{
+			"class BootstrapResources(GeneratedBootstrapResources):\n",
+		}
+		tt.want["test/e2e/service_bootstrap.py"] = []string{
+			"    resources = BootstrapResources(**generated_bootstrap_resources())\n",
+		}
+		for file, wants := range tt.want {
+			for _, want := range wants {
+				if !strings.Contains(contents[file], want) {
+					t.Errorf("%s: expected\n%s\nin %s\n%s", tt.name, want, file, contents[file])
+				}
+			}
+		}
+	}
+}
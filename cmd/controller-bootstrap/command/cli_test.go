@@ -0,0 +1,186 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const testCommitSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// executeCommand runs the root command with the supplied arguments, after
+// resetting the flags set by previous executions
+func executeCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	defer rootCmd.SetArgs(nil)
+	err := execute()
+	return out.String(), err
+}
+
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+func TestGenerateFlagShorthands(t *testing.T) {
+	// The shorthands used by the Makefile
+	shorthands := map[string]string{
+		"aws-service-alias":     "s",
+		"ack-runtime-version":   "r",
+		"aws-sdk-go-version":    "v",
+		"dry-run":               "d",
+		"existing-controller":   "e",
+		"output":                "o",
+		"model-name":            "m",
+		"test-infra-commit-sha": "t",
+	}
+	for name, shorthand := range shorthands {
+		f := templateCmd.Flags().Lookup(name)
+		if f == nil {
+			t.Errorf("generate has no --%s flag", name)
+			continue
+		}
+		if f.Shorthand != shorthand {
+			t.Errorf("got shorthand %q for --%s, want %q", f.Shorthand, name, shorthand)
+		}
+	}
+
+	// An invalid value stops the command before anything is generated
+	_, err := executeCommand(t, "generate", "-s", "ecr", "-r", "v0.19.0", "-v", "v1.44.25",
+		"-d=true", "-e=false", "-o", t.TempDir(), "-m", "", "-t", "abc")
+	if err == nil || !strings.Contains(err.Error(), "--test-infra-commit-sha") {
+		t.Errorf("expected an invalid --test-infra-commit-sha error, got %v", err)
+	}
+	if optServiceAlias != "ecr" || optRuntimeVersion != "v0.19.0" || optAWSSDKGoVersion != "v1.44.25" ||
+		!optDryRun || optExistingController || optModelName != "" {
+		t.Errorf("the shorthand flags were not parsed")
+	}
+}
+
+func TestRequiredFlagsOnGenerate(t *testing.T) {
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+			t.Errorf("persistent flag --%s should not be required", f.Name)
+		}
+	})
+
+	_, err := executeCommand(t, "generate")
+	if err == nil || !strings.Contains(err.Error(), "required flag(s)") {
+		t.Fatalf("expected a required flags error, got %v", err)
+	}
+	for _, name := range []string{"aws-service-alias", "ack-runtime-version", "aws-sdk-go-version", "output", "test-infra-commit-sha"} {
+		if !strings.Contains(err.Error(), `"`+name+`"`) {
+			t.Errorf("expected --%s to be required: %v", name, err)
+		}
+	}
+
+	if _, err = executeCommand(t, "lint-templates"); err != nil {
+		t.Errorf("lint-templates should not require the generate flags: %v", err)
+	}
+	if _, err = executeCommand(t, "lint-templates", "extra"); err == nil {
+		t.Error("expected lint-templates to reject arguments")
+	}
+}
+
+func TestValidateGenerateFlags(t *testing.T) {
+	defer func(runtime, sdk, sha string, jobs int) {
+		optRuntimeVersion, optAWSSDKGoVersion, optTestInfraCommitSHA, optRenderJobs = runtime, sdk, sha, jobs
+	}(optRuntimeVersion, optAWSSDKGoVersion, optTestInfraCommitSHA, optRenderJobs)
+
+	tests := []struct {
+		runtime string
+		sdk     string
+		sha     string
+		jobs    int
+		wantErr string
+	}{
+		{"v0.19.0", "v1.44.25", testCommitSHA, 0, ""},
+		{"v0.19.0-rc.1", "v1.44.25+build.1", testCommitSHA, 4, ""},
+		{"", "", "", 0, ""},
+		{"latest", "latest", testCommitSHA, 0, ""},
+		{"0.19.0", "v1.44.25", testCommitSHA, 0, "--ack-runtime-version"},
+		{"v0.19", "v1.44.25", testCommitSHA, 0, "--ack-runtime-version"},
+		{"v0.19.0", "v01.44.25", testCommitSHA, 0, "--aws-sdk-go-version"},
+		{"v0.19.0", "v1.44.25", testCommitSHA[:39], 0, "--test-infra-commit-sha"},
+		{"v0.19.0", "v1.44.25", strings.ToUpper(testCommitSHA), 0, "--test-infra-commit-sha"},
+		{"v0.19.0", "v1.44.25", testCommitSHA, -1, "--jobs"},
+	}
+	for _, tt := range tests {
+		optRuntimeVersion, optAWSSDKGoVersion, optTestInfraCommitSHA, optRenderJobs = tt.runtime, tt.sdk, tt.sha, tt.jobs
+		err := validateGenerateFlags(templateCmd, nil)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", tt, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected an error about %s, got %v", tt, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateModuleFlags(t *testing.T) {
+	defer func(modulePath, goVersion string) {
+		optModulePath, optGoVersion = modulePath, goVersion
+	}(optModulePath, optGoVersion)
+
+	tests := []struct {
+		modulePath string
+		goVersion  string
+		wantErr    string
+	}{
+		{"", "", ""},
+		{"example.com/team/ecr-controller", "1.18", ""},
+		{"github.com/org/ecr-controller", "1.19.2", ""},
+		{"github.com/org/ecr controller", "", "--module-path"},
+		{"github.com/org/", "", "--module-path"},
+		{"", "go1.18", "--go-version"},
+		{"", "2", "--go-version"},
+	}
+	for _, tt := range tests {
+		optModulePath, optGoVersion = tt.modulePath, tt.goVersion
+		err := validateGenerateFlags(templateCmd, nil)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", tt, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected an error about %s, got %v", tt, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// configFileName is the name of the configuration file discovered in
+	// the output directory when --config is not supplied
+	configFileName = "bootstrap.yaml"
+	// configEnvPrefix prefixes the environment variables which set options,
+	// e.g. ACK_BOOTSTRAP_AWS_SERVICE_ALIAS sets --aws-service-alias
+	configEnvPrefix = "ACK_BOOTSTRAP_"
+)
+
+// pathOptions are the options holding paths, which are resolved relative
+// to the directory of the configuration file that sets them
+var pathOptions = map[string]bool{
+	"output":           true,
+	"template-dir":     true,
+	"template-overlay": true,
+}
+
+// loadConfig sets the options of a command which were not supplied as flags,
+// first from the ACK_BOOTSTRAP_* environment variables and then from the
+// configuration file. Flags take precedence over environment variables,
+// which take precedence over the configuration file.
+func loadConfig(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	if err := loadConfigEnv(flags); err != nil {
+		return err
+	}
+
+	path, err := configPath(flags)
+	if err != nil || path == "" {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %v", err)
+	}
+	var values map[string]interface{}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("cannot parse config file %s: %v", path, err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || !isKnownOption(cmd.Root(), name) {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+		f := flags.Lookup(name)
+		if f == nil || f.Changed {
+			// Options of other commands are ignored, so that the same
+			// file can be used with every command
+			continue
+		}
+		strs, err := configValues(values[name])
+		if err != nil {
+			return fmt.Errorf("%s: option %q: %v", path, name, err)
+		}
+		if len(strs) > 1 && !isSliceFlag(f) {
+			return fmt.Errorf("%s: option %q takes a single value", path, name)
+		}
+		for _, s := range strs {
+			if pathOptions[name] {
+				s = resolveConfigPath(filepath.Dir(path), s)
+			}
+			if err = flags.Set(name, s); err != nil {
+				return fmt.Errorf("%s: option %q: %v", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfigEnv sets the flags which were not supplied from their
+// environment variables. Values of repeatable flags are comma separated.
+func loadConfigEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		env := configEnvVar(f.Name)
+		value, ok := os.LookupEnv(env)
+		if err != nil || f.Changed || !ok {
+			return
+		}
+		strs := []string{value}
+		if isSliceFlag(f) {
+			strs = strings.Split(value, ",")
+		}
+		for _, s := range strs {
+			if setErr := flags.Set(f.Name, s); setErr != nil {
+				err = fmt.Errorf("invalid value %q of %s: %v", value, env, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// configPath returns the path of the configuration file, which is either
+// supplied with --config or discovered in the output directory. An empty
+// path is returned when there is no configuration file.
+func configPath(flags *pflag.FlagSet) (string, error) {
+	if optConfigPath != "" {
+		return optConfigPath, nil
+	}
+	if flags.Lookup("output") == nil || optOutputPath == "" {
+		return "", nil
+	}
+	path := filepath.Join(optOutputPath, configFileName)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// configEnvVar returns the name of the environment variable of an option
+func configEnvVar(name string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// configValues converts a value of the configuration file to flag values
+func configValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]interface{}); ok {
+				return nil, fmt.Errorf("nested lists are not supported")
+			}
+			if _, ok := item.(map[string]interface{}); ok {
+				return nil, fmt.Errorf("maps are not supported")
+			}
+			strs = append(strs, fmt.Sprint(item))
+		}
+		return strs, nil
+	case map[string]interface{}:
+		return nil, fmt.Errorf("maps are not supported")
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// resolveConfigPath resolves a path of the configuration file relative to
+// the directory of the file. Git URLs are returned unchanged.
+func resolveConfigPath(dir, path string) string {
+	if filepath.IsAbs(path) || isGitURL(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// isKnownOption returns whether any command accepts the named option
+func isKnownOption(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, c := range cmd.Commands() {
+		if isKnownOption(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSliceFlag returns whether a flag may be repeated
+func isSliceFlag(f *pflag.Flag) bool {
+	t := f.Value.Type()
+	return strings.HasSuffix(t, "Array") || strings.HasSuffix(t, "Slice")
+}
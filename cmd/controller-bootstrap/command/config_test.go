@@ -0,0 +1,93 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	config := "aws-service-alias: ecr\n" +
+		"model-name: from-file\n" +
+		"output: controller\n" +
+		"resource:\n  - Repository\n  - PullThroughCacheRule\n" +
+		"dry-run: true\n" +
+		"jobs: 2\n"
+	path := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var alias, model, output string
+	var resources []string
+	var dryRun bool
+	var jobs int
+	root := &cobra.Command{Use: "root"}
+	cmd := &cobra.Command{Use: "generate", Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(cmd)
+	cmd.Flags().StringVar(&alias, "aws-service-alias", "", "")
+	cmd.Flags().StringVar(&model, "model-name", "", "")
+	cmd.Flags().StringVar(&output, "output", "", "")
+	cmd.Flags().StringArrayVar(&resources, "resource", nil, "")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "")
+	cmd.Flags().IntVar(&jobs, "jobs", 0, "")
+	if err := cmd.Flags().Parse([]string{"--aws-service-alias", "s3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(p string) { optConfigPath = p }(optConfigPath)
+	optConfigPath = path
+	t.Setenv("ACK_BOOTSTRAP_AWS_SERVICE_ALIAS", "sqs")
+	t.Setenv("ACK_BOOTSTRAP_MODEL_NAME", "from-env")
+	if err := loadConfig(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if alias != "s3" {
+		t.Errorf("flag should take precedence, got aws-service-alias %q", alias)
+	}
+	if model != "from-env" {
+		t.Errorf("environment should take precedence over the file, got model-name %q", model)
+	}
+	if want := filepath.Join(dir, "controller"); output != want {
+		t.Errorf("got output %q, want %q", output, want)
+	}
+	if want := []string{"Repository", "PullThroughCacheRule"}; !reflect.DeepEqual(resources, want) {
+		t.Errorf("got resources %v, want %v", resources, want)
+	}
+	if !dryRun || jobs != 2 {
+		t.Errorf("got dry-run %v and jobs %d, want true and 2", dryRun, jobs)
+	}
+}
+
+func TestLoadConfigUnknownOption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(path, []byte("aws-service-alais: ecr\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{Use: "generate"}
+
+	defer func(p string) { optConfigPath = p }(optConfigPath)
+	optConfigPath = path
+	if err := loadConfig(cmd); err == nil {
+		t.Error("expected an error for an unknown option")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// phaseLoad is reading a template file and its front-matter
+	phaseLoad = "load"
+	// phasePath is rendering the output path of a template file
+	phasePath = "path"
+	// phaseParse is parsing a template file or partial
+	phaseParse = "parse"
+	// phaseExecute is executing a template file
+	phaseExecute = "execute"
+	// phaseWrite is writing a rendered file to the output directory
+	phaseWrite = "write"
+)
+
+// templateError is an error which occurred while generating a single file
+type templateError struct {
+	// File is the template path, or the output path in the write phase
+	File string
+	// Phase is the phase of the generation the error occurred in
+	Phase string
+	Err   error
+}
+
+// newTemplateError returns a templateError for the supplied file and phase
+func newTemplateError(file string, phase string, err error) *templateError {
+	return &templateError{
+		File:  file,
+		Phase: phase,
+		Err:   err,
+	}
+}
+
+// Error returns the error formatted as "file: phase: message"
+func (e *templateError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Phase, e.Message())
+}
+
+// Message returns the error message without the "template: " prefix
+// added by text/template
+func (e *templateError) Message() string {
+	return strings.TrimPrefix(e.Err.Error(), "template: ")
+}
+
+// Unwrap returns the underlying error
+func (e *templateError) Unwrap() error {
+	return e.Err
+}
+
+// templateErrors is the list of templateErrors of a single run, which is
+// returned as a whole instead of failing on the first error
+type templateErrors []*templateError
+
+// Error returns the number of errors followed by one error per line
+func (e templateErrors) Error() string {
+	msgs := []string{fmt.Sprintf("%d template error(s)", len(e))}
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// errorOrNil returns nil for an empty list of errors, so that a nil
+// templateErrors is never returned as a non-nil error
+func (e templateErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// appendTemplateErrors appends err to errs if it is a templateError or
+// templateErrors, and returns any other non-nil error
+func appendTemplateErrors(errs templateErrors, err error) (templateErrors, error) {
+	var tplErrs templateErrors
+	if errors.As(err, &tplErrs) {
+		return append(errs, tplErrs...), nil
+	}
+	var tplErr *templateError
+	if errors.As(err, &tplErr) {
+		return append(errs, tplErr), nil
+	}
+	return errs, err
+}
+
+// printErrorSummary writes a table of the supplied errors
+func printErrorSummary(w io.Writer, errs templateErrors) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tPHASE\tERROR")
+	for _, err := range errs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", err.File, err.Phase, err.Message())
+	}
+	tw.Flush()
+}
@@ -0,0 +1,172 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+)
+
+func TestTemplateErrors(t *testing.T) {
+	var errs templateErrors
+	if err := errs.errorOrNil(); err != nil {
+		t.Errorf("expected no error for an empty list, got %v", err)
+	}
+
+	errs = templateErrors{
+		newTemplateError("README.md.tpl", phaseExecute, errors.New("template: README.md.tpl:1:3: bad")),
+		newTemplateError("go.mod", phaseWrite, errors.New("permission denied")),
+	}
+	want := "2 template error(s)\n" +
+		"README.md.tpl: execute: README.md.tpl:1:3: bad\n" +
+		"go.mod: write: permission denied"
+	if got := errs.errorOrNil().Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAppendTemplateErrors(t *testing.T) {
+	first := newTemplateError("a.tpl", phaseParse, errors.New("a"))
+	second := newTemplateError("b.tpl", phaseExecute, errors.New("b"))
+	third := newTemplateError("c.tpl", phaseWrite, errors.New("c"))
+
+	errs, err := appendTemplateErrors(nil, nil)
+	if len(errs) != 0 || err != nil {
+		t.Errorf("got %v and %v for a nil error", errs, err)
+	}
+	errs, err = appendTemplateErrors(errs, first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errs, err = appendTemplateErrors(errs, fmt.Errorf("wrapped: %w", templateErrors{second, third}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (templateErrors{first, second, third}); !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+
+	other := errors.New("cannot clone repository")
+	if got, err := appendTemplateErrors(errs, other); err != other || len(got) != 3 {
+		t.Errorf("expected other errors to be returned, got %v and %v", got, err)
+	}
+}
+
+func TestPrintErrorSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printErrorSummary(&buf, templateErrors{
+		newTemplateError("README.md.tpl", phaseExecute, errors.New("template: README.md.tpl:1:3: bad")),
+		newTemplateError("test/e2e/resources/repository.yaml", phaseWrite, errors.New("permission denied")),
+	})
+	want := "FILE                                PHASE    ERROR\n" +
+		"README.md.tpl                       execute  README.md.tpl:1:3: bad\n" +
+		"test/e2e/resources/repository.yaml  write    permission denied\n"
+	if buf.String() != want {
+		t.Errorf("got summary\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"README.md.tpl": {Data: []byte("# {{ .ServiceID }}\n")},
+		"OWNERS.tpl":    {Data: []byte("{{ .ServiceID.Owners }}\n")},
+		"NOTICE.tpl":    {Data: []byte("{{ if }}\n")},
+	})
+	defer func(keepGoing bool, output string, format string) {
+		optKeepGoing, optOutputPath, optOutputFormat = keepGoing, output, format
+	}(optKeepGoing, optOutputPath, optOutputFormat)
+	optOutputFormat = outputFormatText
+
+	for _, keepGoing := range []bool{false, true} {
+		optKeepGoing = keepGoing
+		optOutputPath = filepath.Join(t.TempDir(), "controller")
+		var stderr bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetErr(&stderr)
+		report := newGenerateReport()
+		err := generateFiles(context.Background(), cmd, report, pack, testTemplateVars())
+
+		wantErr := "2 file(s) could not be generated, nothing was written"
+		if keepGoing {
+			wantErr = "2 file(s) could not be generated"
+		}
+		if err == nil || err.Error() != wantErr {
+			t.Errorf("keep-going %v: got error %v, want %q", keepGoing, err, wantErr)
+		}
+		summary := stderr.String()
+		for _, want := range []string{"NOTICE.tpl  parse", "OWNERS.tpl  execute"} {
+			if !strings.Contains(summary, want) {
+				t.Errorf("keep-going %v: expected %q in the summary\n%s", keepGoing, want, summary)
+			}
+		}
+		if len(report.Errors) != 2 {
+			t.Errorf("keep-going %v: expected 2 errors in the report, got %v", keepGoing, report.Errors)
+		}
+
+		_, err = os.Stat(filepath.Join(optOutputPath, "README.md"))
+		if written := err == nil; written != keepGoing {
+			t.Errorf("keep-going %v: got README.md written %v", keepGoing, written)
+		}
+		if report.Written != keepGoing {
+			t.Errorf("keep-going %v: got written %v in the report", keepGoing, report.Written)
+		}
+	}
+}
+
+func TestKeepGoingRollback(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"README.md.tpl":  {Data: []byte("# {{ .ServiceID }}\n")},
+		"config/crd.tpl": {Data: []byte("resources: []\n")},
+	})
+	defer func(keepGoing bool, output string, format string) {
+		optKeepGoing, optOutputPath, optOutputFormat = keepGoing, output, format
+	}(optKeepGoing, optOutputPath, optOutputFormat)
+	optKeepGoing = true
+	optOutputFormat = outputFormatText
+	optOutputPath = filepath.Join(t.TempDir(), "controller")
+	// committing config/crd fails as a directory exists at its path
+	if err := os.MkdirAll(filepath.Join(optOutputPath, "config", "crd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetErr(&bytes.Buffer{})
+	report := newGenerateReport()
+	err := generateFiles(context.Background(), cmd, report, pack, testTemplateVars())
+	wantErr := "1 file(s) could not be generated, nothing was written"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("got error %v, want %q", err, wantErr)
+	}
+	if report.Written {
+		t.Error("expected the report to record that nothing was written")
+	}
+	for _, fr := range report.Files {
+		if fr.Status != fileSkipped {
+			t.Errorf("expected %s to be skipped, got %s", fr.Path, fr.Status)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(optOutputPath, "README.md")); !os.IsNotExist(err) {
+		t.Errorf("expected README.md to be rolled back, got %v", err)
+	}
+}
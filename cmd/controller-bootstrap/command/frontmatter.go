@@ -0,0 +1,140 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiter opens and closes the front-matter of a template file
+const frontMatterDelimiter = "---"
+
+// writeMode controls how a rendered file is written to the output directory
+type writeMode string
+
+const (
+	// writeModeOverwrite replaces any existing file. This is the default.
+	writeModeOverwrite writeMode = "overwrite"
+	// writeModeCreateOnly writes the file only if it does not exist yet
+	writeModeCreateOnly writeMode = "create-only"
+	// writeModeAppend appends the rendered contents to any existing file
+	writeModeAppend writeMode = "append"
+)
+
+// frontMatter is the optional YAML header of a template file, delimited by
+// "---" lines, for example:
+//
+//	---
+//	when:
+//	  - not .ExistingController
+//	mode: create-only
+//	perm: "0755"
+//	---
+//
+// The front-matter is stripped from the rendered output.
+type frontMatter struct {
+	// When is a list of template pipelines which must all evaluate to a
+	// non-empty value for the file to be rendered, e.g. `.ServiceModelName`
+	// or `eq .ServicePackageName "s3"`
+	When []string `yaml:"when"`
+	// Mode is the writeMode of the rendered file
+	Mode writeMode `yaml:"mode"`
+	// Perm is the octal permission bits of the rendered file, e.g. "0755".
+	// When empty the permissions are derived from the template file.
+	Perm string `yaml:"perm"`
+}
+
+// splitFrontMatter separates the front-matter from the body of the supplied
+// template file. Files without front-matter return the default frontMatter.
+// A leading "---" line always opens a front-matter block, which must be
+// closed and only contain the keys of frontMatter. Templates whose output
+// starts with a YAML document separator need an empty front-matter first.
+func splitFrontMatter(contents string) (*frontMatter, string, error) {
+	fm := &frontMatter{Mode: writeModeOverwrite}
+	lines := strings.SplitAfter(contents, "\n")
+	if strings.TrimRight(lines[0], "\r\n") != frontMatterDelimiter {
+		return fm, contents, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == frontMatterDelimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, "", fmt.Errorf("front-matter is not closed by a %q line", frontMatterDelimiter)
+	}
+	header := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	dec := yaml.NewDecoder(strings.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(fm); err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("invalid front-matter: %v", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	switch fm.Mode {
+	case "":
+		fm.Mode = writeModeOverwrite
+	case writeModeOverwrite, writeModeCreateOnly, writeModeAppend:
+	default:
+		return nil, "", fmt.Errorf(
+			"invalid front-matter mode %q, expected one of %s, %s or %s",
+			fm.Mode, writeModeOverwrite, writeModeCreateOnly, writeModeAppend,
+		)
+	}
+	if fm.Perm != "" {
+		if _, err := fm.permissions(); err != nil {
+			return nil, "", err
+		}
+	}
+	return fm, body, nil
+}
+
+// permissions returns the permission bits declared in the front-matter
+func (fm *frontMatter) permissions() (os.FileMode, error) {
+	perm, err := strconv.ParseUint(fm.Perm, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("invalid front-matter perm %q, expected octal permission bits such as \"0755\"", fm.Perm)
+	}
+	return os.FileMode(perm), nil
+}
+
+// shouldRender evaluates the `when` conditions of the front-matter against
+// the supplied template data and returns true if all of them hold
+func (fm *frontMatter) shouldRender(data interface{}) (bool, error) {
+	for _, cond := range fm.When {
+		tmp, err := newTemplate("when").Parse(
+			"{{ if " + cond + " }}true{{ end }}",
+		)
+		if err != nil {
+			return false, fmt.Errorf("invalid front-matter condition %q: %v", cond, err)
+		}
+		var buf bytes.Buffer
+		if err = tmp.Execute(&buf, data); err != nil {
+			return false, fmt.Errorf("unable to evaluate front-matter condition %q: %v", cond, err)
+		}
+		if buf.String() != "true" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,183 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		mode     writeMode
+		perm     string
+		when     int
+		body     string
+		wantErr  string
+	}{
+		{
+			name:     "no front-matter",
+			contents: "# README\n---\n",
+			mode:     writeModeOverwrite,
+			body:     "# README\n---\n",
+		},
+		{
+			name:     "front-matter",
+			contents: "---\nwhen:\n  - .ExistingController\nmode: create-only\nperm: \"0755\"\n---\n#!/bin/sh\n",
+			mode:     writeModeCreateOnly,
+			perm:     "0755",
+			when:     1,
+			body:     "#!/bin/sh\n",
+		},
+		{
+			name:     "empty front-matter before a YAML document separator",
+			contents: "---\n---\n---\nkind: Foo\n",
+			mode:     writeModeOverwrite,
+			body:     "---\nkind: Foo\n",
+		},
+		{
+			name:     "unknown key",
+			contents: "---\nmoed: create-only\n---\nbody\n",
+			wantErr:  "invalid front-matter: unmarshal errors:\n  line 1: field moed not found",
+		},
+		{
+			name:     "invalid YAML",
+			contents: "---\nwhen: [\n---\nbody\n",
+			wantErr:  "invalid front-matter",
+		},
+		{
+			name:     "not closed",
+			contents: "---\nmode: create-only\nbody\n",
+			wantErr:  "front-matter is not closed",
+		},
+		{
+			name:     "invalid mode",
+			contents: "---\nmode: replace\n---\n",
+			wantErr:  `invalid front-matter mode "replace"`,
+		},
+		{
+			name:     "invalid perm",
+			contents: "---\nperm: \"0800\"\n---\n",
+			wantErr:  `invalid front-matter perm "0800"`,
+		},
+	}
+	for _, tt := range tests {
+		fm, body, err := splitFrontMatter(tt.contents)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if fm.Mode != tt.mode || fm.Perm != tt.perm || len(fm.When) != tt.when || body != tt.body {
+			t.Errorf("%s: got %+v and body %q", tt.name, fm, body)
+		}
+	}
+}
+
+func TestShouldRender(t *testing.T) {
+	data := testTemplateVars()
+	data.ExistingController = true
+	tests := []struct {
+		when    []string
+		want    bool
+		wantErr bool
+	}{
+		{nil, true, false},
+		{[]string{".ExistingController"}, true, false},
+		{[]string{"not .ExistingController"}, false, false},
+		{[]string{".ExistingController", `eq .ServicePackageName "s3"`}, false, false},
+		{[]string{`eq .ServicePackageName "ecr"`, ".CRDNames"}, true, false},
+		{[]string{".ServiceModelName"}, false, false},
+		{[]string{".Missing"}, false, true},
+		{[]string{"eq ("}, false, true},
+	}
+	for _, tt := range tests {
+		fm := &frontMatter{When: tt.when}
+		got, err := fm.shouldRender(data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: error = %v, wantErr %v", tt.when, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
+func TestCreateOnlyExistingFile(t *testing.T) {
+	outDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outDir, "OWNERS"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	partials := newTemplate(partialsDir)
+	for _, tt := range []struct {
+		file    string
+		mode    writeMode
+		skipped bool
+	}{
+		{"OWNERS", writeModeCreateOnly, true},
+		{"OWNERS", writeModeOverwrite, false},
+		{"NOTICE", writeModeCreateOnly, false},
+	} {
+		target := &renderTarget{tplPath: tt.file + ".tpl", file: tt.file, body: "approvers\n", mode: tt.mode}
+		result := renderFile(partials, target, outDir)
+		if result.err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.file, result.err)
+		}
+		if tt.skipped {
+			if result.skipped == nil || result.skipped.Reason != skipReasonExists {
+				t.Errorf("%s %s: expected the file to be skipped, got %+v", tt.mode, tt.file, result)
+			}
+			continue
+		}
+		if result.file == nil || string(result.file.contents) != "approvers\n" {
+			t.Errorf("%s %s: expected the file to be rendered, got %+v", tt.mode, tt.file, result)
+		}
+	}
+}
+
+func TestMalformedFrontMatter(t *testing.T) {
+	files := fstest.MapFS{
+		"OWNERS.tpl": {Data: []byte("---\nmoed: create-only\n---\napprovers\n")},
+	}
+	_, err := renderTargets(lintTestPack(t, files), testTemplateVars())
+	var errs templateErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected a single template error, got %v", err)
+	}
+	if errs[0].File != "OWNERS.tpl" || errs[0].Phase != phaseLoad {
+		t.Errorf("got error for %s in phase %s", errs[0].File, errs[0].Phase)
+	}
+
+	found := false
+	for _, issue := range lintTemplatePack(lintTestPack(t, files)) {
+		if strings.HasPrefix(issue.String(), "OWNERS.tpl: invalid front-matter") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected lint-templates to report the invalid front-matter")
+	}
+}
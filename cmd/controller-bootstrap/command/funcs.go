@@ -0,0 +1,209 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// timeNow returns the current time and is replaced in unit tests
+var timeNow = time.Now
+
+// templateFuncs is the function map shared by every template rendered
+// by controller-bootstrap
+var templateFuncs = template.FuncMap{
+	"lower":       strings.ToLower,
+	"upper":       strings.ToUpper,
+	"snake":       toSnakeCase,
+	"kebab":       toKebabCase,
+	"camel":       toCamelCase,
+	"pluralize":   pluralizer.Plural,
+	"singularize": pluralizer.Singular,
+	"toYaml":      toYAML,
+	"toJson":      toJSON,
+	"quote":       quote,
+	"indent":      indent,
+	"wrap":        wrap,
+	"default":     defaultValue,
+	"now":         now,
+	"year":        year,
+}
+
+// newTemplate returns a new template with the shared function map which
+// fails on missing map keys instead of rendering "<no value>"
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error")
+}
+
+// splitWords splits the supplied string into words on non-alphanumeric
+// characters and on case changes, keeping acronyms together
+// (e.g. "DBInstanceID" -> ["DB", "Instance", "ID"])
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		boundary := false
+		if unicode.IsUpper(r) {
+			// "fooBar", "s3Bucket" or the end of an acronym as in "DBInstance"
+			boundary = unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+		}
+		if boundary {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// toSnakeCase returns the supplied string in snake_case
+func toSnakeCase(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "_"))
+}
+
+// toKebabCase returns the supplied string in kebab-case
+func toKebabCase(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "-"))
+}
+
+// toCamelCase returns the supplied string in lower camelCase
+func toCamelCase(s string) string {
+	var b strings.Builder
+	for i, word := range splitWords(s) {
+		word = strings.ToLower(word)
+		if i > 0 {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// toYAML returns the YAML representation of the supplied value without
+// the trailing newline
+func toYAML(v interface{}) (string, error) {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// toJSON returns the compact JSON representation of the supplied value
+func toJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// quote returns the supplied value as a double-quoted string literal
+func quote(v interface{}) string {
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// indent pads every non-empty line of the supplied string with the
+// given number of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap breaks the words of the supplied string into lines of at most the
+// given width, words longer than the width are kept on their own line
+func wrap(width int, s string) string {
+	var b strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(s) {
+		switch {
+		case lineLen == 0:
+		case lineLen+1+len(word) > width:
+			b.WriteString("\n")
+			lineLen = 0
+		default:
+			b.WriteString(" ")
+			lineLen++
+		}
+		b.WriteString(word)
+		lineLen += len(word)
+	}
+	return b.String()
+}
+
+// defaultValue returns the supplied value, or def when the value is empty
+// (e.g. `{{ .ServiceModelName | default "ecr" }}`)
+func defaultValue(def interface{}, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if rv.Len() == 0 {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+// now returns the current time
+func now() time.Time {
+	return timeNow()
+}
+
+// year returns the current year, used in copyright notices
+func year() int {
+	return timeNow().Year()
+}
@@ -0,0 +1,183 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func renderFunc(t *testing.T, tpl string, data interface{}) string {
+	t.Helper()
+	tmp, err := template.New("test").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %v", tpl, err)
+	}
+	var buf bytes.Buffer
+	if err = tmp.Execute(&buf, data); err != nil {
+		t.Fatalf("unable to execute %q: %v", tpl, err)
+	}
+	return buf.String()
+}
+
+func TestLower(t *testing.T) {
+	if got := renderFunc(t, `{{ lower "ServiceID" }}`, nil); got != "serviceid" {
+		t.Errorf("expected %q, got %q", "serviceid", got)
+	}
+}
+
+func TestUpper(t *testing.T) {
+	if got := renderFunc(t, `{{ upper "ecr" }}`, nil); got != "ECR" {
+		t.Errorf("expected %q, got %q", "ECR", got)
+	}
+}
+
+func TestSnake(t *testing.T) {
+	cases := map[string]string{
+		"Repository":                    "repository",
+		"PullThroughCacheRule":          "pull_through_cache_rule",
+		"DBInstance":                    "db_instance",
+		"ServiceID":                     "service_id",
+		"S3Bucket":                      "s3_bucket",
+		"Amazon EC2 Container Registry": "amazon_ec2_container_registry",
+		"already_snake":                 "already_snake",
+	}
+	for in, want := range cases {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("snake(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestKebab(t *testing.T) {
+	cases := map[string]string{
+		"PullThroughCacheRule":    "pull-through-cache-rule",
+		"DBClusterParameterGroup": "db-cluster-parameter-group",
+		"ecr_controller":          "ecr-controller",
+	}
+	for in, want := range cases {
+		if got := toKebabCase(in); got != want {
+			t.Errorf("kebab(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestCamel(t *testing.T) {
+	cases := map[string]string{
+		"PullThroughCacheRule": "pullThroughCacheRule",
+		"DBInstance":           "dbInstance",
+		"service_model_name":   "serviceModelName",
+		"":                     "",
+	}
+	for in, want := range cases {
+		if got := toCamelCase(in); got != want {
+			t.Errorf("camel(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	if got := renderFunc(t, `{{ pluralize "Repository" }}`, nil); got != "Repositories" {
+		t.Errorf("expected %q, got %q", "Repositories", got)
+	}
+}
+
+func TestSingularize(t *testing.T) {
+	if got := renderFunc(t, `{{ singularize "Policies" }}`, nil); got != "Policy" {
+		t.Errorf("expected %q, got %q", "Policy", got)
+	}
+}
+
+func TestToYaml(t *testing.T) {
+	data := map[string]interface{}{
+		"names": []string{"Repository", "Policy"},
+	}
+	want := "names:\n    - Repository\n    - Policy"
+	if got := renderFunc(t, `{{ toYaml . }}`, data); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestToJson(t *testing.T) {
+	data := map[string]interface{}{
+		"name": "<name>",
+		"tags": []string{"a", "b"},
+	}
+	want := `{"name":"<name>","tags":["a","b"]}`
+	if got := renderFunc(t, `{{ toJson . }}`, data); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	want := `"say \"hi\""`
+	if got := renderFunc(t, `{{ quote . }}`, `say "hi"`); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := renderFunc(t, `{{ quote 42 }}`, nil); got != `"42"` {
+		t.Errorf("expected %q, got %q", `"42"`, got)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	want := "  a:\n    b\n\n  c"
+	if got := renderFunc(t, `{{ indent 2 . }}`, "a:\n  b\n\nc"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	want := "Amazon ECR is\na managed\ncontainer\nregistry"
+	if got := renderFunc(t, `{{ wrap 13 . }}`, "Amazon ECR  is a\nmanaged container registry"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := renderFunc(t, `{{ wrap 3 . }}`, "a verylongword b"); got != "a\nverylongword\nb" {
+		t.Errorf("expected long words on their own line, got %q", got)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cases := []struct {
+		tpl  string
+		data interface{}
+		want string
+	}{
+		{`{{ . | default "ecr" }}`, "", "ecr"},
+		{`{{ . | default "ecr" }}`, "s3", "s3"},
+		{`{{ . | default 1 }}`, 0, "1"},
+		{`{{ . | default "none" }}`, []string{}, "none"},
+		{`{{ . | default "none" }}`, []string{"a"}, "[a]"},
+		{`{{ .Missing | default "none" }}`, map[string]string{}, "none"},
+	}
+	for _, c := range cases {
+		if got := renderFunc(t, c.tpl, c.data); got != c.want {
+			t.Errorf("%s with %v: expected %q, got %q", c.tpl, c.data, c.want, got)
+		}
+	}
+}
+
+func TestNowAndYear(t *testing.T) {
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time {
+		return time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if got := renderFunc(t, `{{ now.Format "2006-01-02" }}`, nil); got != "2022-06-01" {
+		t.Errorf("expected %q, got %q", "2022-06-01", got)
+	}
+	if got := renderFunc(t, `{{ year }}`, nil); got != "2022" {
+		t.Errorf("expected %q, got %q", "2022", got)
+	}
+}
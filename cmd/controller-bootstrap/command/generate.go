@@ -14,112 +14,432 @@
 package command
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
-	"io/ioutil"
+	"gopkg.in/src-d/go-git.v4"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"text/template"
+	"syscall"
 )
 
 type templateVars struct {
 	*metaVars
-	AWSSDKGoVersion  string
-	RuntimeVersion   string
-	ServiceModelName string
-	//TestInfraCommitSHA  string
+	AWSSDKGoVersion    string
+	RuntimeVersion     string
+	ServiceModelName   string
+	ExistingController bool
+	TestInfraCommitSHA string
+	// ModulePath is the Go module path of the controller
+	ModulePath string
+	// GoVersion is the Go version of the go directive of the controller
+	GoVersion string
+	// CRDVersion is the API version of the custom resources, e.g. v1alpha1
+	CRDVersion string
+	// APIStatus is the maturity status of the CRDVersion in metadata.yaml
+	APIStatus string
+	// ServiceLink is the URL of the service's product page
+	ServiceLink string
+	// GoModRequires are the dependencies of the controller besides the
+	// runtime and aws-sdk-go, at the versions required by the runtime
+	GoModRequires []goModule
+	// GoSum are the go.sum lines of the dependencies, only set with --go-sum
+	GoSum []string
 }
 
 var templateCmd = &cobra.Command{
-	Use:   "generate",
-	Short: "generate template files in an ACK service controller repository",
-	RunE:  generateController,
+	Use:     "generate",
+	Short:   "generate template files in an ACK service controller repository",
+	Args:    cobra.NoArgs,
+	PreRunE: validateGenerateFlags,
+	RunE:    generateController,
+}
+
+func init() {
+	templateCmd.Flags().StringVarP(
+		&optServiceAlias, "aws-service-alias", "s", "", "AWS service alias",
+	)
+	templateCmd.Flags().StringVarP(
+		&optRuntimeVersion, "ack-runtime-version", "r", "", "Version of aws-controllers-k8s/runtime, or latest",
+	)
+	templateCmd.Flags().StringVarP(
+		&optAWSSDKGoVersion, "aws-sdk-go-version", "v", "", "Version of github.com/aws/aws-sdk-go used to infer service metadata and resources, or latest",
+	)
+	templateCmd.Flags().BoolVarP(
+		&optDryRun, "dry-run", "d", false, "Optional: if true, output files to stdout",
+	)
+	templateCmd.Flags().BoolVarP(
+		&optExistingController, "existing-controller", "e", false, "Optional: if true, update the existing service controller",
+	)
+	templateCmd.Flags().StringVarP(
+		&optOutputPath, "output", "o", "", "Path to ACK service controller directory to bootstrap",
+	)
+	templateCmd.Flags().StringVarP(
+		&optModelName, "model-name", "m", "", "Optional: service model name of the corresponding service alias",
+	)
+	templateCmd.Flags().StringVarP(
+		&optTestInfraCommitSHA, "test-infra-commit-sha", "t", "", "Commit SHA of aws-controllers-k8s/test-infra",
+	)
+	templateCmd.Flags().StringVar(
+		&optTestInfraPath, "test-infra-path", "", "Optional: path to a test-infra checkout the commit SHA is validated against, defaults to a test-infra directory next to the output",
+	)
+	templateCmd.Flags().StringVar(
+		&optCRDVersion, "crd-version", "v1alpha1", "Optional: API version of the custom resources",
+	)
+	templateCmd.Flags().StringVar(
+		&optAPIStatus, "api-status", "available", "Optional: maturity status of the API version in metadata.yaml",
+	)
+	templateCmd.Flags().StringVar(
+		&optServiceLink, "service-link", "", "Optional: URL of the service's product page, defaults to https://aws.amazon.com/<service ID>/",
+	)
+	templateCmd.Flags().StringVar(
+		&optServiceDocumentationURL, "service-documentation-url", "", "Optional: URL of the service's documentation, defaults to the API reference of the service model",
+	)
+	templateCmd.Flags().StringVar(
+		&optModulePath, "module-path", "", "Optional: Go module path of the controller, defaults to github.com/aws-controllers-k8s/<service>-controller",
+	)
+	templateCmd.Flags().StringVar(
+		&optGoVersion, "go-version", "", "Optional: Go version of the controller's go.mod, defaults to the one of the runtime",
+	)
+	templateCmd.Flags().StringVar(
+		&optRuntimePath, "ack-runtime-path", "", "Optional: path to a runtime checkout whose go.mod at --ack-runtime-version sets the dependency versions, defaults to the module cache",
+	)
+	templateCmd.Flags().BoolVar(
+		&optGoSum, "go-sum", false, "Optional: if true, generate go.sum from the local module cache",
+	)
+	templateCmd.Flags().StringVar(
+		&optRuntimeRepoURL, "ack-runtime-repo", runtimeRepoURL, "Optional: git repository whose release tags resolve the latest aws-controllers-k8s/runtime version",
+	)
+	templateCmd.Flags().StringArrayVar(
+		&optResources, "resource", nil, "Optional: name of a resource to generate files for, may be repeated, defaults to all resources of the service",
+	)
+	templateCmd.Flags().IntVar(
+		&optRenderJobs, "jobs", 0, "Optional: number of templates rendered concurrently, defaults to the number of CPUs",
+	)
+	templateCmd.Flags().BoolVar(
+		&optKeepGoing, "keep-going", false, "Optional: if true, generate the files which render successfully even if other templates fail",
+	)
+	templateCmd.MarkFlagRequired("aws-service-alias")
+	templateCmd.MarkFlagRequired("ack-runtime-version")
+	templateCmd.MarkFlagRequired("aws-sdk-go-version")
+	templateCmd.MarkFlagRequired("output")
+	templateCmd.MarkFlagRequired("test-infra-commit-sha")
+}
+
+var (
+	// semverRegexp matches the semantic versions of releases, e.g. v0.19.0
+	semverRegexp = regexp.MustCompile(`^v(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+	// commitSHARegexp matches full git commit SHAs
+	commitSHARegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// goVersionRegexp matches the Go versions of go directives, e.g. 1.17
+	goVersionRegexp = regexp.MustCompile(`^1\.(0|[1-9]\d*)(\.(0|[1-9]\d*))?$`)
+	// crdVersionRegexp matches Kubernetes API versions, e.g. v1alpha1
+	crdVersionRegexp = regexp.MustCompile(`^v[1-9]\d*((alpha|beta)[1-9]\d*)?$`)
+	// modulePathRegexp matches module paths made of slash separated elements
+	modulePathRegexp = regexp.MustCompile(`^[A-Za-z0-9_.~-]+(/[A-Za-z0-9_.~-]+)*$`)
+)
+
+// validateGenerateFlags validates the format of the supplied generate flags.
+// Missing required flags are reported by cobra once the flags are validated.
+func validateGenerateFlags(cmd *cobra.Command, args []string) error {
+	versions := []struct {
+		flag  string
+		value string
+	}{
+		{"ack-runtime-version", optRuntimeVersion},
+		{"aws-sdk-go-version", optAWSSDKGoVersion},
+	}
+	for _, v := range versions {
+		if v.value != "" && v.value != versionLatest && !semverRegexp.MatchString(v.value) {
+			return fmt.Errorf("invalid --%s %q, expected a semantic version such as v1.2.3 or latest", v.flag, v.value)
+		}
+	}
+	if optTestInfraCommitSHA != "" && !commitSHARegexp.MatchString(optTestInfraCommitSHA) {
+		return fmt.Errorf("invalid --test-infra-commit-sha %q, expected a full 40 character commit SHA", optTestInfraCommitSHA)
+	}
+	if optGoVersion != "" && !goVersionRegexp.MatchString(optGoVersion) {
+		return fmt.Errorf("invalid --go-version %q, expected a Go version such as 1.17", optGoVersion)
+	}
+	if optModulePath != "" && !modulePathRegexp.MatchString(optModulePath) {
+		return fmt.Errorf("invalid --module-path %q, expected a module path such as github.com/org/repo", optModulePath)
+	}
+	if !crdVersionRegexp.MatchString(optCRDVersion) {
+		return fmt.Errorf("invalid --crd-version %q, expected a Kubernetes API version such as v1alpha1", optCRDVersion)
+	}
+	if optRenderJobs < 0 {
+		return fmt.Errorf("invalid --jobs %d, expected zero or a positive number", optRenderJobs)
+	}
+	return nil
 }
 
 // generateController creates the initial directories and files for a service controller
 // repository by rendering go template files.
 // TODO: When a controller is already existing, then this method only updates the project
 // description files.
-func generateController(cmd *cobra.Command, args []string) error {
-	cd, err := os.Getwd()
+func generateController(cmd *cobra.Command, args []string) (err error) {
+	report := newGenerateReport()
+	if isJSONOutput() {
+		defer func() {
+			if err != nil && len(report.Errors) == 0 {
+				report.addError(err)
+			}
+			if jsonErr := printJSON(cmd.OutOrStdout(), report); jsonErr != nil && err == nil {
+				err = jsonErr
+			}
+			if err != nil {
+				err = &reportedError{err}
+			}
+		}()
+	}
+
+	cacheACKDir, err := ackCacheDir()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := contextWithSigterm(context.Background())
+	defer cancel()
+	if err := ensureSDKRepo(ctx, cacheACKDir); err != nil {
+		return err
+	}
+	if err := resolveLatestVersions(ctx, cacheACKDir); err != nil {
+		return err
+	}
+	report.AWSSDKGoVersion = optAWSSDKGoVersion
+	report.RuntimeVersion = optRuntimeVersion
+	if path := testInfraCheckout(); path != "" {
+		if err := checkTestInfraCommit(path, optTestInfraCommitSHA); err != nil {
+			return err
+		}
+	}
+
+	pack, err := loadTemplatePack(ctx, cacheACKDir)
 	if err != nil {
-		fmt.Printf("unable to determine current working directory: %s\n", err)
-		os.Exit(1)
+		return err
+	}
+
+	requires, goVersion, err := resolveGoModRequires()
+	if err != nil {
+		return err
+	}
+	if optGoVersion != "" {
+		goVersion = optGoVersion
+	}
+	var sums []string
+	if optGoSum {
+		sums = goSumLines(append([]goModule{
+			{runtimeModulePath, optRuntimeVersion},
+			{sdkModulePath, optAWSSDKGoVersion},
+		}, requires...))
 	}
-	tplDir := filepath.Join(cd, "template")
 
-	svcVars, err := getServiceResources()
+	svcVars, apiVersion, err := getServiceResources()
 	if err != nil {
 		return err
 	}
+	report.Service = svcVars
+	report.APIVersion = apiVersion
+	serviceLink := optServiceLink
+	if serviceLink == "" {
+		serviceLink = defaultServiceLink(svcVars.ServiceID)
+	}
+	if optServiceDocumentationURL != "" {
+		svcVars.ServiceDocumentationURL = optServiceDocumentationURL
+	}
+	modulePath := optModulePath
+	if modulePath == "" {
+		modulePath = fmt.Sprintf("github.com/aws-controllers-k8s/%s-controller", svcVars.ServicePackageName)
+	}
 	tplVars := &templateVars{
 		svcVars,
 		optAWSSDKGoVersion,
 		optRuntimeVersion,
 		optModelName,
+		optExistingController,
+		optTestInfraCommitSHA,
+		modulePath,
+		goVersion,
+		optCRDVersion,
+		optAPIStatus,
+		serviceLink,
+		requires,
+		sums,
 	}
 
-	// Append the template files inside the template directory to tplPaths.
-	var tplPaths []string
-	err = filepath.Walk(tplDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			tplPaths = append(tplPaths, path)
+	return generateFiles(ctx, cmd, report, pack, tplVars)
+}
+
+// generateFiles renders the template pack with the supplied template vars
+// and writes the rendered files to the output directory, or prints them in
+// dry-run mode. The files and errors are recorded in the report.
+func generateFiles(
+	ctx context.Context,
+	cmd *cobra.Command,
+	report *generateReport,
+	pack *templatePack,
+	tplVars *templateVars,
+) error {
+	// The errors of the individual template files are collected rather
+	// than failing on the first one, so that all of them are reported
+	var errs templateErrors
+	partials, err := pack.parsePartials()
+	if errs, err = appendTemplateErrors(errs, err); err != nil {
+		return err
+	}
+
+	targets, err := renderTargets(pack, tplVars)
+	if errs, err = appendTemplateErrors(errs, err); err != nil {
+		return err
+	}
+
+	// Render the output files of the resolved template pack in memory
+	// before writing them to the ACK service controller repository
+	files, skipped, err := renderFiles(ctx, partials, targets, optOutputPath, optRenderJobs)
+	if errs, err = appendTemplateErrors(errs, err); err != nil {
+		return err
+	}
+	for _, f := range files {
+		report.addFile(f, optOutputPath)
+	}
+	for _, fr := range skipped {
+		report.Files = append(report.Files, fr)
+		if fr.Reason == skipReasonEmpty && !isJSONOutput() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: skipping %s, template %s rendered an empty file\n", fr.Path, fr.Template)
 		}
-		return nil
+	}
+	sort.SliceStable(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
 	})
+	if len(errs) > 0 && !optKeepGoing {
+		report.markNotWritten()
+		return reportTemplateErrors(cmd, report, errs)
+	}
 
-	// Loop over the template files from the template directory
-	// and parse, render the files in an ACK service controller repository
-	for _, tplPath := range tplPaths {
-		tmp, err := template.ParseFiles(tplPath)
-		if err != nil {
-			return err
+	if optDryRun {
+		if !isJSONOutput() {
+			printDryRun(files)
 		}
-
-		var buf bytes.Buffer
-		if err = tmp.Execute(&buf, tplVars); err != nil {
+	} else {
+		err = writeOutput(ctx, optOutputPath, files, optKeepGoing)
+		var rolledBack *rolledBackError
+		var writeErrs templateErrors
+		if errors.As(err, &rolledBack) {
+			report.markNotWritten()
+		} else {
+			report.Written = true
+			if errors.As(err, &writeErrs) {
+				report.markWriteFailed(writeErrs)
+			}
+		}
+		if errs, err = appendTemplateErrors(errs, err); err != nil {
 			return err
 		}
+	}
+	if len(errs) > 0 {
+		return reportTemplateErrors(cmd, report, errs)
+	}
+	return nil
+}
 
-		file := strings.TrimPrefix(tplPath, tplDir)
-		file = strings.TrimSuffix(file, ".tpl")
-
-		if optDryRun {
-			fmt.Printf("============================= %s ======================================\n", file)
-			fmt.Println(strings.TrimSpace(buf.String()))
+// printDryRun prints the rendered files to stdout
+func printDryRun(files []*renderedFile) {
+	for _, f := range files {
+		fmt.Printf("============================= %s ======================================\n", f.file)
+		if f.symlink != "" {
+			fmt.Printf("symlink to %s\n", f.symlink)
 			continue
 		}
+		fmt.Println(strings.TrimSpace(string(f.contents)))
+	}
+}
 
-		outPath := filepath.Join(optOutputPath, file)
-		outDir := filepath.Dir(outPath)
-		if _, err = ensureDir(outDir); err != nil {
-			return err
-		}
-		if err = ioutil.WriteFile(outPath, buf.Bytes(), 0666); err != nil {
-			return err
-		}
+// reportTemplateErrors adds the supplied errors to the report, prints a
+// summary table of them unless the output is JSON, and returns the error
+// the command fails with
+func reportTemplateErrors(cmd *cobra.Command, report *generateReport, errs templateErrors) error {
+	cmd.SilenceUsage = true
+	report.addError(errs)
+	if !isJSONOutput() {
+		printErrorSummary(cmd.ErrOrStderr(), errs)
 	}
-	return nil
+	if report.Written {
+		return fmt.Errorf("%d file(s) could not be generated", len(errs))
+	}
+	return fmt.Errorf("%d file(s) could not be generated, nothing was written", len(errs))
 }
 
-// ensureDir makes sure that a supplied directory exists and
-// returns whether the directory already existed.
-func ensureDir(fp string) (bool, error) {
-	fi, err := os.Stat(fp)
+// ackCacheDir returns the directory in which controller-bootstrap caches
+// the git repositories it clones
+func ackCacheDir() (string, error) {
+	hd, err := os.UserHomeDir()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false, os.MkdirAll(fp, os.ModePerm)
+		return "", fmt.Errorf("unable to determine $HOME: %v", err)
+	}
+	return filepath.Join(hd, ".cache", "aws-controllers-k8s"), nil
+}
+
+// ensureSDKRepo ensures that we have a git clone'd copy of the aws-sdk-go
+// repository, which we use model JSON files from.
+func ensureSDKRepo(
+	ctx context.Context,
+	cacheDir string,
+) error {
+	var err error
+	srcPath := filepath.Join(cacheDir, "src")
+	if err = os.MkdirAll(srcPath, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Clone repository if it doen't exist
+	sdkDir = filepath.Join(srcPath, "aws-sdk-go")
+
+	if _, err = os.Stat(sdkDir); os.IsNotExist(err) {
+
+		ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
+		defer cancel()
+		err = CloneRepository(ct, sdkDir, sdkRepoURL)
+		if err != nil {
+			return fmt.Errorf("canot clone repository: %v", err)
 		}
-		return false, err
 	}
-	if !fi.IsDir() {
-		return false, fmt.Errorf("expected %s to be a directory", fp)
+	return err
+}
+
+// CloneRepository clones a git repository into a given directory.
+// Calling this function is equivalent to executing `git clone $repositoryURL $path`
+func CloneRepository(ctx context.Context, path, repositoryURL string) error {
+	_, err := git.PlainCloneContext(ctx, path, false, &git.CloneOptions{
+		URL:      repositoryURL,
+		Progress: nil,
+		// Clone and fetch all tags
+		Tags: git.AllTags,
+	})
+	return err
+}
+
+func contextWithSigterm(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	signalCh := make(chan os.Signal, 1)
+
+	// recreate the context.CancelFunc
+	cancelFunc := func() {
+		signal.Stop(signalCh)
+		cancel()
 	}
 
-	return true, nil
+	// notify on SIGINT or SIGTERM
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signalCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancelFunc
 }
@@ -0,0 +1,257 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+const (
+	runtimeModulePath = "github.com/aws-controllers-k8s/runtime"
+	sdkModulePath     = "github.com/aws/aws-sdk-go"
+	// defaultGoVersion is the Go version of the generated go.mod when the
+	// go.mod of the runtime cannot be found
+	defaultGoVersion = "1.17"
+)
+
+// goModule is a module path and version of a go.mod require directive
+type goModule struct {
+	Path    string
+	Version string
+}
+
+// runtimeDependencies are the modules required by the generated controllers
+// besides the runtime and aws-sdk-go. Their versions are taken from the
+// go.mod of the runtime, the versions below are only used when it cannot
+// be found.
+var runtimeDependencies = []goModule{
+	{"github.com/go-logr/logr", "v1.2.0"},
+	{"github.com/spf13/pflag", "v1.0.5"},
+	{"k8s.io/api", "v0.23.0"},
+	{"k8s.io/apimachinery", "v0.23.0"},
+	{"k8s.io/client-go", "v0.23.0"},
+	{"sigs.k8s.io/controller-runtime", "v0.11.0"},
+}
+
+// resolveGoModRequires returns the runtimeDependencies with the versions
+// required by the go.mod of the selected runtime version, along with the Go
+// version of its go directive
+func resolveGoModRequires() ([]goModule, string, error) {
+	data, err := readRuntimeGoMod()
+	if err != nil {
+		return nil, "", err
+	}
+	if data == nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot find the go.mod of %s %s, using default dependency versions\n", runtimeModulePath, optRuntimeVersion)
+	}
+	versions := parseGoModRequires(data)
+
+	requires := make([]goModule, 0, len(runtimeDependencies))
+	for _, dep := range runtimeDependencies {
+		if v, ok := versions[dep.Path]; ok {
+			dep.Version = v
+		}
+		requires = append(requires, dep)
+	}
+	goVersion := parseGoModGoVersion(data)
+	if goVersion == "" {
+		goVersion = defaultGoVersion
+	}
+	return requires, goVersion, nil
+}
+
+// readRuntimeGoMod returns the go.mod of the selected runtime version from
+// the runtime checkout supplied with --ack-runtime-path, the local module
+// cache or a runtime checkout next to the output directory, in that order.
+// Nil is returned when none of them has it.
+func readRuntimeGoMod() ([]byte, error) {
+	if optRuntimePath != "" {
+		return readGoModAtTag(optRuntimePath, optRuntimeVersion)
+	}
+	data, err := ioutil.ReadFile(moduleCacheFile(runtimeModulePath, optRuntimeVersion, ".mod"))
+	if err == nil {
+		return data, nil
+	}
+	path := filepath.Join(filepath.Dir(filepath.Clean(optOutputPath)), "runtime")
+	if _, err = os.Stat(filepath.Join(path, ".git")); err != nil {
+		return nil, nil
+	}
+	data, err = readGoModAtTag(path, optRuntimeVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return nil, nil
+	}
+	return data, nil
+}
+
+// readGoModAtTag returns the go.mod of a git checkout at the supplied tag
+func readGoModAtTag(path, tag string) ([]byte, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open runtime checkout %s: %v", path, err)
+	}
+	ref, err := repo.Tag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("tag %s not found in runtime checkout %s", tag, path)
+	}
+	hash := ref.Hash()
+	// Annotated tags point to a tag object rather than the commit
+	if tagObj, err := repo.TagObject(hash); err == nil {
+		hash = tagObj.Target
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tag %s of runtime checkout %s: %v", tag, path, err)
+	}
+	f, err := commit.File("go.mod")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read go.mod of runtime checkout %s at %s: %v", path, tag, err)
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+// parseGoModRequires returns the versions of the modules required by a
+// go.mod file, keyed by module path
+func parseGoModRequires(data []byte) map[string]string {
+	versions := map[string]string{}
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		case !inBlock:
+			continue
+		}
+		if len(fields) == 2 {
+			versions[fields[0]] = fields[1]
+		}
+	}
+	return versions
+}
+
+// parseGoModGoVersion returns the Go version of the go directive of a
+// go.mod file, or an empty string if it has none
+func parseGoModGoVersion(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "go" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// goSumLines returns the go.sum lines of the supplied modules, as far as
+// they are found in the local module cache. The lines of the modules they
+// require are left to go mod tidy, which knows which of them the build needs.
+func goSumLines(requires []goModule) []string {
+	seen := map[goModule]bool{}
+	var lines []string
+	missing := 0
+	for _, mod := range requires {
+		if seen[mod] {
+			continue
+		}
+		seen[mod] = true
+
+		goMod, err := ioutil.ReadFile(moduleCacheFile(mod.Path, mod.Version, ".mod"))
+		if err != nil {
+			missing++
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s/go.mod %s", mod.Path, mod.Version, goModHash(goMod)))
+		if zipHash, err := ioutil.ReadFile(moduleCacheFile(mod.Path, mod.Version, ".ziphash")); err == nil {
+			lines = append(lines, fmt.Sprintf("%s %s %s", mod.Path, mod.Version, strings.TrimSpace(string(zipHash))))
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d module(s) are missing from the module cache, go.sum is incomplete until go mod tidy is run\n", missing)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// goModHash returns the go.sum hash of a go.mod file, which is the h1
+// directory hash of a directory containing only the go.mod file
+func goModHash(data []byte) string {
+	summary := fmt.Sprintf("%x  %s\n", sha256.Sum256(data), "go.mod")
+	sum := sha256.Sum256([]byte(summary))
+	return "h1:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// moduleCacheFile returns the path of a file of a module version in the
+// download cache of the local module cache, e.g. the .mod or .ziphash file
+func moduleCacheFile(path, version, ext string) string {
+	return filepath.Join(goModCache(), "cache", "download", escapeModulePath(path), "@v", escapeModulePath(version)+ext)
+}
+
+// goModCache returns the directory of the local module cache
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(hd, "go", "pkg", "mod")
+}
+
+// escapeModulePath escapes the upper case letters of a module path or
+// version the way the module cache does, e.g. "Azure" becomes "!azure"
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+const jmespathGoMod = "module github.com/jmespath/go-jmespath\n\ngo 1.14\n\n" +
+	"require github.com/jmespath/go-jmespath/internal/testify v1.5.1\n"
+
+// writeModuleCacheFile writes a file of a module version to the download
+// cache of a temporary module cache, which GOMODCACHE is set to
+func writeModuleCacheFile(t *testing.T, path, version, ext, contents string) {
+	t.Helper()
+	t.Setenv("GOMODCACHE", t.TempDir())
+	file := moduleCacheFile(path, version, ext)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseGoModRequires(t *testing.T) {
+	goMod := `module github.com/aws-controllers-k8s/runtime
+
+go 1.17
+
+require github.com/go-logr/logr v1.2.3 // indirect
+
+require (
+	// k8s dependencies
+	k8s.io/api v0.24.1
+	sigs.k8s.io/controller-runtime v0.12.1 // indirect
+)
+
+replace k8s.io/client-go => k8s.io/client-go v0.24.0
+`
+	want := map[string]string{
+		"github.com/go-logr/logr":        "v1.2.3",
+		"k8s.io/api":                     "v0.24.1",
+		"sigs.k8s.io/controller-runtime": "v0.12.1",
+	}
+	if got := parseGoModRequires([]byte(goMod)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestResolveGoModRequiresFromModuleCache(t *testing.T) {
+	defer func(version, path, output string) {
+		optRuntimeVersion, optRuntimePath, optOutputPath = version, path, output
+	}(optRuntimeVersion, optRuntimePath, optOutputPath)
+	optRuntimeVersion, optRuntimePath, optOutputPath = "v0.20.0", "", t.TempDir()
+	writeModuleCacheFile(t, runtimeModulePath, "v0.20.0", ".mod",
+		"module github.com/aws-controllers-k8s/runtime\n\ngo 1.18\n\nrequire (\n\tk8s.io/api v0.24.2\n\tsigs.k8s.io/controller-runtime v0.12.1\n)\n")
+
+	requires, goVersion, err := resolveGoModRequires()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if goVersion != "1.18" {
+		t.Errorf("got Go version %q, want the runtime's 1.18", goVersion)
+	}
+	got := map[string]string{}
+	for _, mod := range requires {
+		got[mod.Path] = mod.Version
+	}
+	if got["k8s.io/api"] != "v0.24.2" || got["sigs.k8s.io/controller-runtime"] != "v0.12.1" {
+		t.Errorf("expected the versions of the runtime's go.mod, got %v", got)
+	}
+	if got["k8s.io/client-go"] != "v0.23.0" {
+		t.Errorf("expected the default version of modules the runtime does not require, got %v", got)
+	}
+}
+
+func TestGoSumLines(t *testing.T) {
+	writeModuleCacheFile(t, "github.com/jmespath/go-jmespath", "v0.4.0", ".mod", jmespathGoMod)
+	zipHash := moduleCacheFile("github.com/jmespath/go-jmespath", "v0.4.0", ".ziphash")
+	if err := ioutil.WriteFile(zipHash, []byte("h1:BEgLn5cpjn8UN1mAw4NjwDrS35OdebyEtFe+9YPoQUg=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The modules required by the supplied ones are not added
+	testify := moduleCacheFile("github.com/jmespath/go-jmespath/internal/testify", "v1.5.1", ".mod")
+	if err := os.MkdirAll(filepath.Dir(testify), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testify, []byte("module github.com/jmespath/go-jmespath/internal/testify\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := goSumLines([]goModule{{"github.com/jmespath/go-jmespath", "v0.4.0"}})
+	want := []string{
+		"github.com/jmespath/go-jmespath v0.4.0 h1:BEgLn5cpjn8UN1mAw4NjwDrS35OdebyEtFe+9YPoQUg=",
+		"github.com/jmespath/go-jmespath v0.4.0/go.mod h1:T8mJZnbsbmF+m6zOOFylbeCJqk5+pHWvzYPziyZiYoo=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEscapeModulePath(t *testing.T) {
+	if got := escapeModulePath("github.com/Azure/go-autorest"); got != "github.com/!azure/go-autorest" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestGoModTemplate(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(base, "go.mod.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := lintTestPack(t, fstest.MapFS{"go.mod.tpl": {Data: data}})
+	tplVars := testTemplateVars()
+	tplVars.ModulePath = "github.com/aws-controllers-k8s/ecr-controller"
+	tplVars.GoVersion = "1.17"
+	tplVars.RuntimeVersion = "v0.19.0"
+	tplVars.AWSSDKGoVersion = "v1.44.25"
+
+	requires := map[string][]goModule{
+		"no requires": nil,
+		"requires":    {{"github.com/go-logr/logr", "v1.2.0"}, {"k8s.io/api", "v0.23.0"}},
+	}
+	for name, mods := range requires {
+		tplVars.GoModRequires = mods
+		want := "module github.com/aws-controllers-k8s/ecr-controller\n\ngo 1.17\n\nrequire (\n" +
+			"\tgithub.com/aws-controllers-k8s/runtime v0.19.0\n" +
+			"\tgithub.com/aws/aws-sdk-go v1.44.25\n"
+		for _, mod := range mods {
+			want += "\t" + mod.Path + " " + mod.Version + "\n"
+		}
+		want += ")\n"
+
+		targets, err := renderTargets(pack, tplVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, _, err := renderFiles(context.Background(), newTemplate(""), targets, t.TempDir(), 1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got := string(rendered[0].contents); got != want {
+			t.Errorf("%s: got go.mod\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+func TestGoSumTemplate(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(base, "go.sum.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := lintTestPack(t, fstest.MapFS{"go.sum.tpl": {Data: data}})
+
+	// go.sum is left out without --go-sum, rather than rendered empty
+	tplVars := testTemplateVars()
+	targets, err := renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 0 {
+		t.Errorf("expected go.sum to be skipped without --go-sum, got %d target(s)", len(targets))
+	}
+
+	tplVars.GoSum = []string{
+		"github.com/jmespath/go-jmespath v0.4.0 h1:BEgLn5cpjn8UN1mAw4NjwDrS35OdebyEtFe+9YPoQUg=",
+		"github.com/jmespath/go-jmespath v0.4.0/go.mod h1:T8mJZnbsbmF+m6zOOFylbeCJqk5+pHWvzYPziyZiYoo=",
+	}
+	targets, err = renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, _, err := renderFiles(context.Background(), newTemplate(""), targets, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := tplVars.GoSum[0] + "\n" + tplVars.GoSum[1] + "\n"
+	if len(rendered) != 1 {
+		t.Fatalf("expected a single rendered file, got %d", len(rendered))
+	}
+	if got := string(rendered[0].contents); got != want {
+		t.Errorf("got go.sum %q, want %q", got, want)
+	}
+}
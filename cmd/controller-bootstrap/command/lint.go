@@ -0,0 +1,513 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:          "lint-templates",
+	Short:        "validate the template files against the template variables",
+	Args:         cobra.NoArgs,
+	RunE:         lintTemplates,
+	SilenceUsage: true,
+}
+
+var (
+	templateVarsType         = reflect.TypeOf(templateVars{})
+	metaVarsType             = reflect.TypeOf(metaVars{})
+	resourceTemplateVarsType = reflect.TypeOf(resourceTemplateVars{})
+)
+
+// lintIssue is a problem found in a template file
+type lintIssue struct {
+	// pos is the "file:line" position of the issue, empty when the issue
+	// is not specific to a template file
+	pos     string
+	message string
+	// warning is true for issues which do not fail the lint, such as
+	// template variables that no template uses
+	warning bool
+}
+
+// String returns the issue formatted as "file:line: message"
+func (i lintIssue) String() string {
+	msg := i.message
+	if i.warning {
+		msg = "warning: " + msg
+	}
+	if i.pos == "" {
+		return msg
+	}
+	return i.pos + ": " + msg
+}
+
+// lintTemplates parses every file of the resolved template pack against
+// templateVars and reports parse errors, unknown fields and template
+// variables that are not used by any template
+func lintTemplates(cmd *cobra.Command, args []string) (err error) {
+	report := &lintReport{Issues: []lintIssueReport{}, Errors: []errorReport{}}
+	if isJSONOutput() {
+		defer func() {
+			if err != nil && len(report.Issues) == 0 {
+				report.Errors = append(report.Errors, errorReport{Message: err.Error()})
+			}
+			if jsonErr := printJSON(cmd.OutOrStdout(), report); jsonErr != nil && err == nil {
+				err = jsonErr
+			}
+			if err != nil {
+				err = &reportedError{err}
+			}
+		}()
+	}
+
+	cacheDir, err := ackCacheDir()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := contextWithSigterm(context.Background())
+	defer cancel()
+	pack, err := loadTemplatePack(ctx, cacheDir)
+	if err != nil {
+		return err
+	}
+
+	numErrors := 0
+	for _, issue := range lintTemplatePack(pack) {
+		if !issue.warning {
+			numErrors++
+		}
+		if isJSONOutput() {
+			report.Issues = append(report.Issues, lintIssueReport{
+				Position: issue.pos,
+				Message:  issue.message,
+				Warning:  issue.warning,
+			})
+			continue
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), issue)
+	}
+	if numErrors > 0 {
+		return fmt.Errorf("found %d error(s) in the template files", numErrors)
+	}
+	return nil
+}
+
+// lintTemplatePack returns the issues found in the supplied template pack
+func lintTemplatePack(pack *templatePack) []lintIssue {
+	c := &lintChecker{
+		files:   map[string]string{},
+		seen:    map[string]bool{},
+		used:    map[string]bool{},
+		checked: map[string]bool{},
+	}
+
+	// Partials are parsed one by one to report each parse error, and are
+	// type checked with the data they are invoked with
+	partials := newTemplate(partialsDir)
+	for _, partial := range pack.Partials() {
+		name := partialName(partial)
+		c.files[name] = partial
+		contents, err := pack.ReadFile(partial)
+		if err != nil {
+			c.report(lintIssue{pos: partial, message: err.Error()})
+			continue
+		}
+		text := strings.TrimSuffix(string(contents), "\n")
+		if _, err = partials.New(name).Parse(text); err != nil {
+			c.reportParseError(err)
+		}
+	}
+
+	for _, tplPath := range pack.Paths() {
+		c.lintFile(pack, partials, tplPath)
+	}
+
+	issues := c.issues
+	for _, field := range reflect.VisibleFields(templateVarsType) {
+		if field.Anonymous || !field.IsExported() || c.used[field.Name] {
+			continue
+		}
+		// skip the fields shadowed by templateVars' own fields
+		if visible, _ := templateVarsType.FieldByName(field.Name); !reflect.DeepEqual(visible.Index, field.Index) {
+			continue
+		}
+		issues = append(issues, lintIssue{
+			message: fmt.Sprintf("template variable .%s is not used by any template", field.Name),
+			warning: true,
+		})
+	}
+	return issues
+}
+
+// lintChecker type checks template parse trees against the template data
+type lintChecker struct {
+	// set is the template set the template names are looked up in
+	set *template.Template
+	// files maps the partial names to their template file paths
+	files map[string]string
+	// issues are the issues found so far, seen is used to deduplicate them
+	issues []lintIssue
+	seen   map[string]bool
+	// used contains the names of the template variables referenced
+	used map[string]bool
+	// checked contains the templates already checked for a data type
+	checked map[string]bool
+}
+
+// report records the supplied issue unless it was already reported
+func (c *lintChecker) report(issue lintIssue) {
+	if c.seen[issue.String()] {
+		return
+	}
+	c.seen[issue.String()] = true
+	c.issues = append(c.issues, issue)
+}
+
+// reportParseError records a template parse error, which is formatted
+// as "template: name:line: message"
+func (c *lintChecker) reportParseError(err error) {
+	msg := strings.TrimPrefix(err.Error(), "template: ")
+	parts := strings.SplitN(msg, ": ", 2)
+	if len(parts) != 2 {
+		c.report(lintIssue{message: msg})
+		return
+	}
+	c.report(lintIssue{pos: c.position(parts[0]), message: parts[1]})
+}
+
+// position maps a "name:line[:col]" template location to a "file:line"
+// position, replacing partial names with their file paths
+func (c *lintChecker) position(location string) string {
+	parts := strings.Split(location, ":")
+	if file, ok := c.files[parts[0]]; ok {
+		parts[0] = file
+	}
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, ":")
+}
+
+// lintFile checks the path, the front-matter and the body of a template file
+func (c *lintChecker) lintFile(pack *templatePack, partials *template.Template, tplPath string) {
+	target, fm, err := newRenderTarget(pack, tplPath)
+	if err != nil {
+		c.report(lintIssue{pos: tplPath, message: err.Error()})
+		return
+	}
+	dot := templateVarsType
+	perResource, err := referencesResource(tplPath)
+	if err != nil {
+		c.report(lintIssue{pos: tplPath, message: err.Error()})
+		return
+	}
+	if perResource {
+		dot = resourceTemplateVarsType
+	}
+
+	if strings.Contains(tplPath, "{{") {
+		c.lintText(newTemplate(""), tplPath+" (path)", tplPath, dot)
+	}
+	for _, cond := range fm.When {
+		c.lintText(newTemplate(""), tplPath+" (when)", "{{ if "+cond+" }}{{ end }}", dot)
+	}
+	if target.symlink != "" {
+		return
+	}
+
+	set, err := partials.Clone()
+	if err != nil {
+		c.report(lintIssue{pos: tplPath, message: err.Error()})
+		return
+	}
+	c.lintText(set, tplPath, target.body, dot)
+}
+
+// lintText parses the supplied text as the named template of the set and
+// type checks it against the supplied data type
+func (c *lintChecker) lintText(set *template.Template, name string, text string, dot reflect.Type) {
+	if _, err := set.New(name).Parse(text); err != nil {
+		c.reportParseError(err)
+		return
+	}
+	c.set = set
+	c.checked = map[string]bool{}
+	c.checkTemplate(name, dot)
+}
+
+// checkTemplate type checks the named template of the set once per data type
+func (c *lintChecker) checkTemplate(name string, dot reflect.Type) {
+	key := fmt.Sprintf("%s|%v", name, dot)
+	if c.checked[key] {
+		return
+	}
+	c.checked[key] = true
+	tmp := c.set.Lookup(name)
+	if tmp == nil || tmp.Tree == nil {
+		return
+	}
+	c.walk(tmp.Tree, tmp.Tree.Root, dot, map[string]reflect.Type{"$": dot})
+}
+
+// walk type checks the supplied node. A nil type means the type is unknown
+// and is not checked any further.
+func (c *lintChecker) walk(
+	tree *parse.Tree,
+	node parse.Node,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(tree, child, dot, vars)
+		}
+	case *parse.ActionNode:
+		c.pipeType(tree, n.Pipe, dot, vars)
+	case *parse.IfNode:
+		c.pipeType(tree, n.Pipe, dot, vars)
+		c.walk(tree, n.List, dot, copyVars(vars))
+		c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.WithNode:
+		scope := copyVars(vars)
+		t := c.pipeType(tree, n.Pipe, dot, scope)
+		c.walk(tree, n.List, t, scope)
+		c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.RangeNode:
+		scope := copyVars(vars)
+		key, elem := rangeTypes(c.cmdsType(tree, n.Pipe, dot, scope))
+		switch len(n.Pipe.Decl) {
+		case 1:
+			scope[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			scope[n.Pipe.Decl[0].Ident[0]] = key
+			scope[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		c.walk(tree, n.List, elem, scope)
+		c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.TemplateNode:
+		t := c.pipeType(tree, n.Pipe, dot, vars)
+		if c.set.Lookup(n.Name) == nil {
+			c.report(lintIssue{
+				pos:     c.nodePosition(tree, n),
+				message: fmt.Sprintf("no such template %q", n.Name),
+			})
+			return
+		}
+		c.checkTemplate(n.Name, t)
+	}
+}
+
+// pipeType type checks the commands of the supplied pipeline, declares its
+// variables and returns the type of the pipeline's value
+func (c *lintChecker) pipeType(
+	tree *parse.Tree,
+	pipe *parse.PipeNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	t := c.cmdsType(tree, pipe, dot, vars)
+	if pipe != nil {
+		for _, decl := range pipe.Decl {
+			vars[decl.Ident[0]] = t
+		}
+	}
+	return t
+}
+
+// cmdsType type checks the commands of the supplied pipeline and returns
+// the type of the last command
+func (c *lintChecker) cmdsType(
+	tree *parse.Tree,
+	pipe *parse.PipeNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	if pipe == nil {
+		return dot
+	}
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		t = c.cmdType(tree, cmd, dot, vars)
+	}
+	return t
+}
+
+// cmdType type checks the arguments of the supplied command and returns
+// the type of its value
+func (c *lintChecker) cmdType(
+	tree *parse.Tree,
+	cmd *parse.CommandNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	for _, arg := range cmd.Args[1:] {
+		c.argType(tree, arg, dot, vars)
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return funcReturnType(ident.Ident)
+	}
+	return c.argType(tree, cmd.Args[0], dot, vars)
+}
+
+// argType returns the type of the supplied command argument
+func (c *lintChecker) argType(
+	tree *parse.Tree,
+	arg parse.Node,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	switch a := arg.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fieldType(tree, a, dot, a.Ident)
+	case *parse.VariableNode:
+		return c.fieldType(tree, a, vars[a.Ident[0]], a.Ident[1:])
+	case *parse.ChainNode:
+		return c.fieldType(tree, a, c.argType(tree, a.Node, dot, vars), a.Field)
+	case *parse.PipeNode:
+		return c.pipeType(tree, a, dot, copyVars(vars))
+	case *parse.StringNode:
+		return reflect.TypeOf("")
+	case *parse.BoolNode:
+		return reflect.TypeOf(true)
+	}
+	return nil
+}
+
+// fieldType resolves the supplied chain of field names starting from the
+// supplied type and reports the fields which do not exist
+func (c *lintChecker) fieldType(
+	tree *parse.Tree,
+	node parse.Node,
+	t reflect.Type,
+	idents []string,
+) reflect.Type {
+	for _, ident := range idents {
+		if t == nil {
+			return nil
+		}
+		if method, ok := t.MethodByName(ident); ok {
+			t = knownType(method.Type.Out(0))
+			continue
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := t.FieldByName(ident)
+			if !ok || !field.IsExported() {
+				c.report(lintIssue{
+					pos:     c.nodePosition(tree, node),
+					message: fmt.Sprintf("unknown field .%s in type %s", ident, t.Name()),
+				})
+				return nil
+			}
+			if t == templateVarsType || t == metaVarsType || t == resourceTemplateVarsType {
+				c.used[ident] = true
+			}
+			t = knownType(field.Type)
+		case reflect.Map:
+			t = knownType(t.Elem())
+		case reflect.Interface:
+			return nil
+		default:
+			c.report(lintIssue{
+				pos:     c.nodePosition(tree, node),
+				message: fmt.Sprintf("can't evaluate field .%s in type %s", ident, t),
+			})
+			return nil
+		}
+	}
+	return t
+}
+
+// nodePosition returns the "file:line" position of the supplied node
+func (c *lintChecker) nodePosition(tree *parse.Tree, node parse.Node) string {
+	location, _ := tree.ErrorContext(node)
+	return c.position(location)
+}
+
+// funcReturnType returns the type of the value returned by the named
+// template function, or nil if it is unknown
+func funcReturnType(name string) reflect.Type {
+	if fn, ok := templateFuncs[name]; ok {
+		ft := reflect.TypeOf(fn)
+		if ft.NumOut() > 0 {
+			return knownType(ft.Out(0))
+		}
+		return nil
+	}
+	switch name {
+	case "not", "eq", "ne", "lt", "le", "gt", "ge":
+		return reflect.TypeOf(true)
+	case "len":
+		return reflect.TypeOf(0)
+	case "print", "printf", "println", "html", "js", "urlquery":
+		return reflect.TypeOf("")
+	}
+	return nil
+}
+
+// rangeTypes returns the key and element types when ranging over a value
+// of the supplied type
+func rangeTypes(t reflect.Type) (reflect.Type, reflect.Type) {
+	if t == nil {
+		return nil, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0), knownType(t.Elem())
+	case reflect.Map:
+		return knownType(t.Key()), knownType(t.Elem())
+	case reflect.Int:
+		return reflect.TypeOf(0), reflect.TypeOf(0)
+	}
+	return nil, nil
+}
+
+// knownType returns nil for interface types, whose dynamic type is unknown
+func knownType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+// copyVars returns a copy of the variables in scope, so that variables
+// declared inside a block do not leak out of it
+func copyVars(vars map[string]reflect.Type) map[string]reflect.Type {
+	scope := make(map[string]reflect.Type, len(vars))
+	for name, t := range vars {
+		scope[name] = t
+	}
+	return scope
+}
@@ -0,0 +1,100 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func lintTestPack(t *testing.T, files fstest.MapFS) *templatePack {
+	t.Helper()
+	pack := newTemplatePack()
+	if err := pack.addLayer(&templateLayer{FS: files}); err != nil {
+		t.Fatalf("unable to load template pack: %v", err)
+	}
+	return pack
+}
+
+func TestLintEmbeddedTemplates(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatalf("unable to load the embedded templates: %v", err)
+	}
+	pack := newTemplatePack()
+	if err = pack.addLayer(base); err != nil {
+		t.Fatalf("unable to load the embedded templates: %v", err)
+	}
+	for _, issue := range lintTemplatePack(pack) {
+		t.Error(issue)
+	}
+}
+
+func TestLintTemplatePackIssues(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"README.md.tpl": {Data: []byte(
+			"# {{ .ServiceFullName }}\n" +
+				"{{ .ServiceFullname }}\n" +
+				"{{ range .CRDNames }}{{ .Name }}{{ end }}\n" +
+				"{{ template \"footer.md\" . }}\n",
+		)},
+		"go.mod.tpl":      {Data: []byte("{{ if }}\n")},
+		"_partials/a.tpl": {Data: []byte("{{ .Resource.Nam }}\n")},
+		"{{.Resource.Snake}}.yaml.tpl": {Data: []byte(
+			"---\nwhen:\n  - .Missing\n---\n{{ template \"a\" . }}\n",
+		)},
+	})
+
+	got := map[string]bool{}
+	for _, issue := range lintTemplatePack(pack) {
+		got[issue.String()] = true
+	}
+	for _, want := range []string{
+		"README.md.tpl:2: unknown field .ServiceFullname in type templateVars",
+		"README.md.tpl:3: can't evaluate field .Name in type string",
+		`README.md.tpl:4: no such template "footer.md"`,
+		"go.mod.tpl:1: missing value for if",
+		"_partials/a.tpl:1: unknown field .Nam in type resourceVars",
+		"{{.Resource.Snake}}.yaml.tpl (when):1: unknown field .Missing in type resourceTemplateVars",
+		"warning: template variable .AWSSDKGoVersion is not used by any template",
+	} {
+		if !got[want] {
+			t.Errorf("expected issue %q, got %v", want, got)
+		}
+	}
+	if got["warning: template variable .ServiceFullName is not used by any template"] {
+		t.Errorf("expected .ServiceFullName to be reported as used")
+	}
+}
+
+func TestLintTemplatePathAndScopes(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"{{.Resource.Kebab}}/{{.ServiceID}}.tpl": {Data: []byte(
+			"{{ $svc := .ServicePackageName }}" +
+				"{{ with .Resource }}{{ .Plural }} {{ $svc | upper }} {{ $.ServiceID }}{{ end }}" +
+				"{{ range $i, $name := .CRDNames }}{{ $i }} {{ snake $name }}{{ end }}\n",
+		)},
+		"{{.ServiceFullname}}.tpl": {Data: []byte("x\n")},
+	})
+	want := "{{.ServiceFullname}}.tpl (path):1: unknown field .ServiceFullname in type templateVars"
+	var errors []string
+	for _, issue := range lintTemplatePack(pack) {
+		if !issue.warning {
+			errors = append(errors, issue.String())
+		}
+	}
+	if len(errors) != 1 || errors[0] != want {
+		t.Errorf("expected only issue %q, got %v", want, errors)
+	}
+}
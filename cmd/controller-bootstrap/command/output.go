@@ -0,0 +1,330 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputTransaction writes the rendered files to the output directory as a
+// whole. Files are first staged in a temporary directory next to the output
+// directory and are only moved into the output directory once all of them
+// are staged. If moving a file fails, or the process is interrupted, the
+// files already moved are rolled back to the previous contents of the
+// output directory.
+type outputTransaction struct {
+	// outDir is the output directory
+	outDir string
+	// tmpDir contains the staged files and the backups of the replaced files
+	tmpDir string
+	// staged contains the slash separated paths of the staged files
+	staged []string
+	// committed contains the paths of the files moved into outDir, along
+	// with whether a previous file was backed up
+	committed []committedFile
+	// createdDirs contains the directories created in outDir, in the order
+	// they were created
+	createdDirs []string
+}
+
+// committedFile is a file moved from the staging directory into the
+// output directory
+type committedFile struct {
+	file     string
+	backedUp bool
+}
+
+// newOutputTransaction returns an outputTransaction for the supplied
+// output directory, creating it if needed
+func newOutputTransaction(outDir string) (*outputTransaction, error) {
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+	tx := &outputTransaction{
+		outDir: outDir,
+	}
+	// The staging directory is a sibling of the output directory so that
+	// the staged files can be renamed into the output directory, which
+	// requires the parent directory to be writable
+	parent := filepath.Dir(outDir)
+	if err = tx.mkdirAll(parent); err != nil {
+		tx.removeCreatedDirs()
+		return nil, err
+	}
+	tx.tmpDir, err = ioutil.TempDir(parent, "."+filepath.Base(outDir)+"-bootstrap-")
+	if err != nil {
+		tx.removeCreatedDirs()
+		return nil, fmt.Errorf(
+			"cannot write %s: the generated files are staged in its parent directory %s, which must be writable: %v",
+			outDir, parent, err,
+		)
+	}
+	if err = tx.mkdirAll(outDir); err != nil {
+		tx.cleanup()
+		tx.removeCreatedDirs()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// stagedPath returns the path of the supplied file in the staging directory
+func (tx *outputTransaction) stagedPath(file string) string {
+	return filepath.Join(tx.tmpDir, "staged", filepath.FromSlash(file))
+}
+
+// backupPath returns the path of the backup of the supplied file
+func (tx *outputTransaction) backupPath(file string) string {
+	return filepath.Join(tx.tmpDir, "backup", filepath.FromSlash(file))
+}
+
+// outPath returns the path of the supplied file in the output directory
+func (tx *outputTransaction) outPath(file string) string {
+	return filepath.Join(tx.outDir, filepath.FromSlash(file))
+}
+
+// stageFile stages the rendered contents of the supplied file, which is
+// created with the supplied permissions minus the umask of the process. In
+// append mode the staged file starts with the contents of the existing file.
+func (tx *outputTransaction) stageFile(
+	file string,
+	contents []byte,
+	mode writeMode,
+	perm os.FileMode,
+) error {
+	staged := tx.stagedPath(file)
+	if err := os.MkdirAll(filepath.Dir(staged), os.ModePerm); err != nil {
+		return err
+	}
+	if mode == writeModeAppend {
+		existing, err := ioutil.ReadFile(tx.outPath(file))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		contents = append(existing, contents...)
+	}
+	if err := ioutil.WriteFile(staged, contents, perm); err != nil {
+		return err
+	}
+	tx.staged = append(tx.staged, file)
+	return nil
+}
+
+// stageSymlink stages a symlink to the supplied destination
+func (tx *outputTransaction) stageSymlink(file string, dest string) error {
+	staged := tx.stagedPath(file)
+	if err := os.MkdirAll(filepath.Dir(staged), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Symlink(dest, staged); err != nil {
+		return err
+	}
+	tx.staged = append(tx.staged, file)
+	return nil
+}
+
+// commit moves the staged files into the output directory, replacing the
+// existing files. On error, or if the context is cancelled, the output
+// directory is rolled back to its previous contents.
+func (tx *outputTransaction) commit(ctx context.Context) error {
+	for _, file := range tx.staged {
+		if err := ctx.Err(); err != nil {
+			return tx.abort(fmt.Errorf("interrupted while writing %s: %v", tx.outDir, err))
+		}
+		if err := tx.commitFile(file); err != nil {
+			return newTemplateError(file, phaseWrite, tx.abort(err))
+		}
+	}
+	return nil
+}
+
+// commitFile moves a single staged file into the output directory, backing
+// up the file it replaces
+func (tx *outputTransaction) commitFile(file string) error {
+	outPath := tx.outPath(file)
+	if err := tx.mkdirAll(filepath.Dir(outPath)); err != nil {
+		return err
+	}
+	backedUp := false
+	fi, err := os.Lstat(outPath)
+	switch {
+	case err == nil && fi.IsDir():
+		return fmt.Errorf("cannot write %s: a directory exists at that path", outPath)
+	case err == nil:
+		backup := tx.backupPath(file)
+		if err = os.MkdirAll(filepath.Dir(backup), os.ModePerm); err != nil {
+			return err
+		}
+		if err = os.Rename(outPath, backup); err != nil {
+			return err
+		}
+		backedUp = true
+	case !os.IsNotExist(err):
+		return err
+	}
+	if err = os.Rename(tx.stagedPath(file), outPath); err != nil {
+		if backedUp {
+			os.Rename(tx.backupPath(file), outPath)
+		}
+		return err
+	}
+	tx.committed = append(tx.committed, committedFile{file: file, backedUp: backedUp})
+	return nil
+}
+
+// abort rolls back the transaction and returns the supplied error along
+// with any error encountered while rolling back
+func (tx *outputTransaction) abort(cause error) error {
+	if err := tx.rollback(); err != nil {
+		return fmt.Errorf("%v; rollback of %s failed: %v", cause, tx.outDir, err)
+	}
+	return cause
+}
+
+// rollback restores the files replaced by the committed files, removes
+// the newly created files and directories and cleans up the staging
+// directory
+func (tx *outputTransaction) rollback() error {
+	var errs []string
+	for i := len(tx.committed) - 1; i >= 0; i-- {
+		c := tx.committed[i]
+		outPath := tx.outPath(c.file)
+		if err := os.Remove(outPath); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if c.backedUp {
+			if err := os.Rename(tx.backupPath(c.file), outPath); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	tx.committed = nil
+	tx.removeCreatedDirs()
+	// keep the backups around if any of them could not be restored
+	if len(errs) > 0 {
+		return fmt.Errorf("%s (backups are kept in %s)", strings.Join(errs, "; "), tx.tmpDir)
+	}
+	return tx.cleanup()
+}
+
+// cleanup removes the staging directory
+func (tx *outputTransaction) cleanup() error {
+	if tx.tmpDir == "" {
+		return nil
+	}
+	return os.RemoveAll(tx.tmpDir)
+}
+
+// mkdirAll creates the supplied directory and any missing parents,
+// recording the created directories so they can be removed on rollback
+func (tx *outputTransaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		fi, err := os.Stat(d)
+		if err == nil {
+			if !fi.IsDir() {
+				return fmt.Errorf("expected %s to be a directory", d)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], os.ModePerm); err != nil {
+			return err
+		}
+		tx.createdDirs = append(tx.createdDirs, missing[i])
+	}
+	return nil
+}
+
+// removeCreatedDirs removes the directories created by the transaction,
+// deepest first. Directories which are not empty are kept.
+func (tx *outputTransaction) removeCreatedDirs() {
+	for i := len(tx.createdDirs) - 1; i >= 0; i-- {
+		os.Remove(tx.createdDirs[i])
+	}
+	tx.createdDirs = nil
+}
+
+// rolledBackError is returned by writeOutput when the output directory was
+// left unchanged, i.e. none of the files were written
+type rolledBackError struct {
+	error
+}
+
+// Unwrap returns the underlying error
+func (e *rolledBackError) Unwrap() error {
+	return e.error
+}
+
+// writeOutput writes the rendered files into the output directory in a
+// single outputTransaction. With keepGoing, the files which fail to be
+// staged are left out and reported as a templateErrors once the other
+// files are written, instead of aborting the transaction. Errors which
+// leave the output directory unchanged are returned as a rolledBackError.
+func writeOutput(
+	ctx context.Context,
+	outDir string,
+	files []*renderedFile,
+	keepGoing bool,
+) error {
+	tx, err := newOutputTransaction(outDir)
+	if err != nil {
+		return &rolledBackError{err}
+	}
+	var errs templateErrors
+	for _, f := range files {
+		if err = ctx.Err(); err != nil {
+			return &rolledBackError{tx.abort(fmt.Errorf("interrupted while staging %s: %v", outDir, err))}
+		}
+		if f.symlink != "" {
+			err = tx.stageSymlink(f.file, f.symlink)
+		} else {
+			err = tx.stageFile(f.file, f.contents, f.mode, f.perm)
+		}
+		if err != nil {
+			tplErr := newTemplateError(f.file, phaseWrite, err)
+			if !keepGoing {
+				tplErr.Err = tx.abort(err)
+				return &rolledBackError{tplErr}
+			}
+			errs = append(errs, tplErr)
+		}
+	}
+	if err = tx.commit(ctx); err != nil {
+		// the files which failed to be staged are reported along with
+		// the one which failed to be committed
+		if errs, err = appendTemplateErrors(errs, err); err != nil {
+			return &rolledBackError{err}
+		}
+		return &rolledBackError{errs}
+	}
+	if err = tx.cleanup(); err != nil {
+		return err
+	}
+	return errs.errorOrNil()
+}
@@ -0,0 +1,273 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRenderedFile returns a renderedFile with the supplied contents
+func testRenderedFile(file string, contents string, mode writeMode) *renderedFile {
+	return &renderedFile{
+		renderTarget: &renderTarget{file: file, mode: mode, perm: 0666},
+		contents:     []byte(contents),
+	}
+}
+
+// readTree returns the contents of the regular files below dir keyed by
+// their slash separated paths, along with its directories suffixed by "/"
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			tree[filepath.ToSlash(rel)+"/"] = ""
+			return nil
+		}
+		contents, err := os.ReadFile(path)
+		tree[filepath.ToSlash(rel)] = string(contents)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// testOutputDir returns an output directory containing the supplied files,
+// inside a parent directory which contains nothing else
+func testOutputDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	outDir := filepath.Join(t.TempDir(), "controller")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, contents := range files {
+		path := filepath.Join(outDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(file, "/") {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return outDir
+}
+
+// assertNoStagingDir fails if the staging directory next to the output
+// directory was not removed
+func assertNoStagingDir(t *testing.T, outDir string) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(outDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != filepath.Base(outDir) {
+			t.Errorf("expected the staging directory to be removed, found %s", entry.Name())
+		}
+	}
+}
+
+// cancelAfterContext is cancelled once Err was called n times, to cancel
+// a transaction at a given file
+type cancelAfterContext struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestWriteOutputCommit(t *testing.T) {
+	outDir := testOutputDir(t, map[string]string{
+		"README.md":  "old readme",
+		"OWNERS":     "old owners",
+		".gitignore": "bin\n",
+	})
+	files := []*renderedFile{
+		testRenderedFile("README.md", "new readme", writeModeOverwrite),
+		testRenderedFile(".gitignore", "build\n", writeModeAppend),
+		testRenderedFile("test/e2e/__init__.py", "import pytest", writeModeOverwrite),
+	}
+	if err := writeOutput(context.Background(), outDir, files, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"README.md":            "new readme",
+		"OWNERS":               "old owners",
+		".gitignore":           "bin\nbuild\n",
+		"test/":                "",
+		"test/e2e/":            "",
+		"test/e2e/__init__.py": "import pytest",
+	}
+	if got := readTree(t, outDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("got output tree %v, want %v", got, want)
+	}
+	assertNoStagingDir(t, outDir)
+}
+
+func TestWriteOutputCreatesOutputDir(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "src", "controller")
+	files := []*renderedFile{testRenderedFile("README.md", "readme", writeModeOverwrite)}
+	if err := writeOutput(context.Background(), outDir, files, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readTree(t, outDir); !reflect.DeepEqual(got, map[string]string{"README.md": "readme"}) {
+		t.Errorf("got output tree %v", got)
+	}
+}
+
+func TestWriteOutputRollback(t *testing.T) {
+	existing := map[string]string{
+		"README.md":      "old readme",
+		"config/":        "",
+		"config/crd/":    "",
+		"config/crd/foo": "a directory where a file is generated",
+	}
+	outDir := testOutputDir(t, existing)
+	files := []*renderedFile{
+		testRenderedFile("README.md", "new readme", writeModeOverwrite),
+		testRenderedFile("apis/v1alpha1/doc.go", "package v1alpha1", writeModeOverwrite),
+		// committing this file fails as a directory exists at its path
+		testRenderedFile("config/crd", "kustomization", writeModeOverwrite),
+		testRenderedFile("OWNERS", "approvers", writeModeOverwrite),
+	}
+	for _, keepGoing := range []bool{false, true} {
+		err := writeOutput(context.Background(), outDir, files, keepGoing)
+		var errs templateErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].File != "config/crd" || errs[0].Phase != phaseWrite {
+			t.Fatalf("keep-going %v: expected a write error for config/crd, got %v", keepGoing, err)
+		}
+		var rolledBack *rolledBackError
+		if !errors.As(err, &rolledBack) {
+			t.Errorf("keep-going %v: expected a rolledBackError, got %T", keepGoing, err)
+		}
+		if got := readTree(t, outDir); !reflect.DeepEqual(got, existing) {
+			t.Errorf("keep-going %v: expected the output directory to be rolled back, got %v", keepGoing, got)
+		}
+		assertNoStagingDir(t, outDir)
+	}
+}
+
+func TestWriteOutputCancelled(t *testing.T) {
+	existing := map[string]string{"README.md": "old readme"}
+	files := []*renderedFile{
+		testRenderedFile("README.md", "new readme", writeModeOverwrite),
+		testRenderedFile("apis/v1alpha1/doc.go", "package v1alpha1", writeModeOverwrite),
+		testRenderedFile("OWNERS", "approvers", writeModeOverwrite),
+	}
+	tests := map[string]int{
+		"while staging":    1,
+		"while committing": len(files) + 2,
+	}
+	for name, n := range tests {
+		outDir := testOutputDir(t, existing)
+		ctx := &cancelAfterContext{Context: context.Background(), n: n}
+		err := writeOutput(ctx, outDir, files, false)
+		if err == nil || !strings.Contains(err.Error(), "interrupted") {
+			t.Errorf("%s: expected an interrupted error, got %v", name, err)
+		}
+		if got := readTree(t, outDir); !reflect.DeepEqual(got, existing) {
+			t.Errorf("%s: expected the output directory to be rolled back, got %v", name, got)
+		}
+		assertNoStagingDir(t, outDir)
+	}
+
+	// a cancelled context never creates the output directory
+	outDir := filepath.Join(t.TempDir(), "controller")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := writeOutput(ctx, outDir, files, false); err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", outDir, err)
+	}
+	assertNoStagingDir(t, outDir)
+}
+
+func TestWriteOutputKeepGoing(t *testing.T) {
+	files := []*renderedFile{
+		testRenderedFile("OWNERS", "approvers", writeModeOverwrite),
+		// staging fails as OWNERS is staged as a file
+		testRenderedFile("OWNERS/README.md", "readme", writeModeOverwrite),
+		testRenderedFile("README.md", "readme", writeModeOverwrite),
+	}
+
+	outDir := testOutputDir(t, nil)
+	err := writeOutput(context.Background(), outDir, files, false)
+	var tplErr *templateError
+	if !errors.As(err, &tplErr) || tplErr.File != "OWNERS/README.md" {
+		t.Fatalf("expected a write error for OWNERS/README.md, got %v", err)
+	}
+	if got := readTree(t, outDir); len(got) != 0 {
+		t.Errorf("expected nothing to be written without --keep-going, got %v", got)
+	}
+	assertNoStagingDir(t, outDir)
+
+	outDir = testOutputDir(t, nil)
+	err = writeOutput(context.Background(), outDir, files, true)
+	var errs templateErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].File != "OWNERS/README.md" {
+		t.Fatalf("expected a single write error for OWNERS/README.md, got %v", err)
+	}
+	want := map[string]string{"OWNERS": "approvers", "README.md": "readme"}
+	if got := readTree(t, outDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the other files to be written with --keep-going, got %v", got)
+	}
+	assertNoStagingDir(t, outDir)
+}
+
+func TestNewOutputTransactionUnwritableParent(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	parent := t.TempDir()
+	outDir := filepath.Join(parent, "controller")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(parent, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(parent, 0755)
+
+	_, err := newOutputTransaction(outDir)
+	if err == nil || !strings.Contains(err.Error(), "must be writable") {
+		t.Errorf("expected an error about the parent directory, got %v", err)
+	}
+}
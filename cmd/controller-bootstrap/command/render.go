@@ -0,0 +1,460 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// resourceVars describes a single inferred custom resource. Template files
+// whose path references `.Resource` are rendered once per resource, for
+// example `test/e2e/resources/{{.Resource.Snake}}.yaml.tpl`.
+type resourceVars struct {
+	// Name is the resource name, e.g. "PullThroughCacheRule"
+	Name string
+	// Snake is the snake_case resource name, e.g. "pull_through_cache_rule"
+	Snake string
+	// Kebab is the kebab-case resource name, e.g. "pull-through-cache-rule"
+	Kebab string
+	// Camel is the lower camelCase resource name, e.g. "pullThroughCacheRule"
+	Camel string
+	// Lower is the lowercased resource name, e.g. "pullthroughcacherule"
+	Lower string
+	// Plural is the pluralized resource name, e.g. "PullThroughCacheRules"
+	Plural string
+	// Spec is a sample spec with the required fields of the Create input
+	// shape set to placeholder values
+	Spec map[string]interface{}
+	// ExampleSpec is the Spec with example values in place of the
+	// placeholders, e.g. for the samples of the OLM bundle
+	ExampleSpec map[string]interface{}
+	// Read is the API operation describing the resource, nil if the
+	// service has none
+	Read *readOperation
+}
+
+// resourceTemplateVars is the data a per-resource template is rendered with
+type resourceTemplateVars struct {
+	*templateVars
+	Resource *resourceVars
+}
+
+// renderTarget is a single output file rendered from a template file
+type renderTarget struct {
+	// tplPath is the path of the template file inside the template pack
+	tplPath string
+	// file is the slash separated output path relative to the output directory
+	file string
+	// body is the contents of the template file without its front-matter
+	body string
+	// mode is the writeMode of the output file
+	mode writeMode
+	// perm is the permission bits of the output file before the umask
+	perm os.FileMode
+	// symlink is the destination of the output file when the template file
+	// is a symlink, in which case it is not rendered
+	symlink string
+	// data is the value the template file is executed with
+	data interface{}
+}
+
+// renderedFile is a renderTarget rendered in memory
+type renderedFile struct {
+	*renderTarget
+	contents []byte
+}
+
+// newResourceVars returns the resourceVars for the supplied resource name
+// and model, which is nil if the resource has no Create operation
+func newResourceVars(name string, model *crdModel) *resourceVars {
+	if model == nil {
+		model = &crdModel{}
+	}
+	return &resourceVars{
+		Name:        name,
+		Snake:       toSnakeCase(name),
+		Kebab:       toKebabCase(name),
+		Camel:       toCamelCase(name),
+		Lower:       strings.ToLower(name),
+		Plural:      pluralizer.Plural(name),
+		Spec:        model.spec,
+		ExampleSpec: exampleSpec(model.spec),
+		Read:        model.read,
+	}
+}
+
+// Resources returns the resourceVars of every custom resource, for the
+// templates which list the resources in a single file
+func (v templateVars) Resources() []*resourceVars {
+	resources := make([]*resourceVars, 0, len(v.CRDNames))
+	for _, crdName := range v.CRDNames {
+		resources = append(resources, newResourceVars(crdName, v.crdModels[crdName]))
+	}
+	return resources
+}
+
+// renderTargets returns the output files to render for every template file
+// in the pack. Template paths may contain template expressions, and paths
+// referencing `.Resource` fan out into one output file per custom resource.
+// Output files whose front-matter conditions do not hold are left out.
+//
+// The targets of the template files which fail to load are left out and
+// returned along with a templateErrors listing the failures.
+func renderTargets(pack *templatePack, tplVars *templateVars) ([]*renderTarget, error) {
+	targets := []*renderTarget{}
+	var errs templateErrors
+	seen := map[string]string{}
+	add := func(proto *renderTarget, fm *frontMatter, data interface{}) *templateError {
+		tplPath := proto.tplPath
+		render, err := fm.shouldRender(data)
+		if err != nil {
+			return newTemplateError(tplPath, phaseLoad, err)
+		}
+		if !render {
+			return nil
+		}
+		file, err := outputPath(tplPath, data)
+		if err != nil {
+			return newTemplateError(tplPath, phasePath, err)
+		}
+		if other, ok := seen[file]; ok {
+			return newTemplateError(tplPath, phasePath, fmt.Errorf("%s also renders to %s", other, file))
+		}
+		seen[file] = tplPath
+		target := *proto
+		target.file = file
+		target.data = data
+		targets = append(targets, &target)
+		return nil
+	}
+
+	for _, tplPath := range pack.Paths() {
+		proto, fm, err := newRenderTarget(pack, tplPath)
+		if err != nil {
+			errs = append(errs, newTemplateError(tplPath, phaseLoad, err))
+			continue
+		}
+		perResource, err := referencesResource(tplPath)
+		if err != nil {
+			errs = append(errs, newTemplateError(tplPath, phasePath, err))
+			continue
+		}
+		if !perResource {
+			if tplErr := add(proto, fm, tplVars); tplErr != nil {
+				errs = append(errs, tplErr)
+			}
+			continue
+		}
+		for _, crdName := range tplVars.CRDNames {
+			data := &resourceTemplateVars{
+				templateVars: tplVars,
+				Resource:     newResourceVars(crdName, tplVars.crdModels[crdName]),
+			}
+			if tplErr := add(proto, fm, data); tplErr != nil {
+				errs = append(errs, tplErr)
+			}
+		}
+	}
+	return targets, errs.errorOrNil()
+}
+
+// renderResult is the outcome of rendering a single renderTarget
+type renderResult struct {
+	// file is nil if the target was skipped or failed to render
+	file *renderedFile
+	// skipped is set if the target was skipped
+	skipped *fileReport
+	err     *templateError
+}
+
+const (
+	// skipReasonExists is the reason create-only files are skipped
+	skipReasonExists = "create-only file already exists"
+	// skipReasonEmpty is the reason empty renders are skipped
+	skipReasonEmpty = "rendered an empty file"
+)
+
+// renderFiles renders every target in memory, so that nothing is written
+// to the output directory unless all templates render successfully.
+// Create-only targets which already exist in the output directory, and
+// targets rendering to an empty file, are skipped and returned separately.
+//
+// Targets are rendered concurrently by a bounded pool of workers. The
+// rendered files, warnings and errors are returned in the order of the
+// targets regardless of the order in which they were rendered. The files
+// which rendered successfully are returned along with a templateErrors
+// listing every template which failed, rather than only the first one.
+func renderFiles(
+	ctx context.Context,
+	partials *template.Template,
+	targets []*renderTarget,
+	outDir string,
+	workers int,
+) ([]*renderedFile, []*fileReport, error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]renderResult, len(targets))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// drain the remaining targets once cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+				results[i] = renderFile(partials, targets[i], outDir)
+			}
+		}()
+	}
+feed:
+	for i := range targets {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("interrupted while rendering templates: %v", err)
+	}
+
+	files := []*renderedFile{}
+	skipped := []*fileReport{}
+	var errs templateErrors
+	for _, result := range results {
+		if result.skipped != nil {
+			skipped = append(skipped, result.skipped)
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+		if result.file != nil {
+			files = append(files, result.file)
+		}
+	}
+	return files, skipped, errs.errorOrNil()
+}
+
+// renderFile renders a single target in memory
+func renderFile(partials *template.Template, target *renderTarget, outDir string) renderResult {
+	if target.mode == writeModeCreateOnly {
+		outPath := filepath.Join(outDir, filepath.FromSlash(target.file))
+		if _, err := os.Lstat(outPath); err == nil {
+			return renderResult{skipped: newSkippedFileReport(target, skipReasonExists)}
+		}
+	}
+	if target.symlink != "" {
+		return renderResult{file: &renderedFile{renderTarget: target}}
+	}
+
+	tmp, err := partials.Clone()
+	if err != nil {
+		return renderResult{err: newTemplateError(target.tplPath, phaseParse, err)}
+	}
+	if tmp, err = tmp.New(target.tplPath).Parse(target.body); err != nil {
+		return renderResult{err: newTemplateError(target.tplPath, phaseParse, err)}
+	}
+	var buf bytes.Buffer
+	if err = tmp.Execute(&buf, target.data); err != nil {
+		return renderResult{err: newTemplateError(target.tplPath, phaseExecute, err)}
+	}
+	if len(bytes.TrimSpace(buf.Bytes())) == 0 {
+		return renderResult{skipped: newSkippedFileReport(target, skipReasonEmpty)}
+	}
+	return renderResult{file: &renderedFile{
+		renderTarget: target,
+		contents:     buf.Bytes(),
+	}}
+}
+
+// newSkippedFileReport returns the fileReport of a skipped target
+func newSkippedFileReport(target *renderTarget, reason string) *fileReport {
+	return &fileReport{
+		Path:     target.file,
+		Template: target.tplPath,
+		Status:   fileSkipped,
+		Reason:   reason,
+	}
+}
+
+// newRenderTarget returns the renderTarget for the supplied template path,
+// without its output path and data, along with the template's front-matter
+func newRenderTarget(pack *templatePack, tplPath string) (*renderTarget, *frontMatter, error) {
+	tplMode, err := pack.Mode(tplPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	target := &renderTarget{
+		tplPath: tplPath,
+		mode:    writeModeOverwrite,
+		perm:    0666,
+	}
+	if tplMode&fs.ModeSymlink != 0 {
+		if target.symlink, err = pack.Readlink(tplPath); err != nil {
+			return nil, nil, err
+		}
+		if err = checkSymlinkDest(target.symlink); err != nil {
+			return nil, nil, err
+		}
+		return target, &frontMatter{Mode: writeModeOverwrite}, nil
+	}
+
+	contents, err := pack.ReadFile(tplPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	fm, body, err := splitFrontMatter(string(contents))
+	if err != nil {
+		return nil, nil, err
+	}
+	target.body = body
+	target.mode = fm.Mode
+	switch {
+	case fm.Perm != "":
+		if target.perm, err = fm.permissions(); err != nil {
+			return nil, nil, err
+		}
+	case tplMode&0111 != 0:
+		// like git, only the executable bits of the template are honoured
+		target.perm = 0777
+	}
+	return target, fm, nil
+}
+
+// checkSymlinkDest returns an error if the destination of a symlink in the
+// template tree is absolute or contains "..", either of which could point
+// outside of the output directory
+func checkSymlinkDest(dest string) error {
+	if filepath.IsAbs(dest) || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "\\") {
+		return fmt.Errorf("symlink destination %s must be a relative path", dest)
+	}
+	isSeparator := func(r rune) bool { return r == '/' || r == '\\' }
+	for _, elem := range strings.FieldsFunc(dest, isSeparator) {
+		if elem == ".." {
+			return fmt.Errorf("symlink destination %s must not contain \"..\"", dest)
+		}
+	}
+	return nil
+}
+
+// outputPath renders the supplied template path with the template data and
+// returns the output path without the template suffix
+func outputPath(tplPath string, data interface{}) (string, error) {
+	file := tplPath
+	if strings.Contains(tplPath, "{{") {
+		tmp, err := newTemplate(tplPath).Parse(tplPath)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse template path %s: %v", tplPath, err)
+		}
+		var buf bytes.Buffer
+		if err = tmp.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("unable to render template path %s: %v", tplPath, err)
+		}
+		file = buf.String()
+	}
+	file = strings.TrimSuffix(file, templateSuffix)
+	// an empty path segment means an expression rendered an empty string
+	if file == "" || strings.HasPrefix(file, "/") || strings.HasSuffix(file, "/") ||
+		strings.Contains(file, "//") {
+		return "", fmt.Errorf("template path %s renders to invalid output path %q", tplPath, file)
+	}
+	file = path.Clean(file)
+	if file == "." || file == ".." || strings.HasPrefix(file, "../") {
+		return "", fmt.Errorf("template path %s renders to output path %q outside of the output directory", tplPath, file)
+	}
+	return file, nil
+}
+
+// referencesResource returns true if the supplied template path refers to
+// the `.Resource` field and must be rendered once per custom resource
+func referencesResource(tplPath string) (bool, error) {
+	if !strings.Contains(tplPath, "{{") {
+		return false, nil
+	}
+	trees, err := parse.Parse(tplPath, tplPath, "{{", "}}", templateFuncs)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse template path %s: %v", tplPath, err)
+	}
+	for _, tree := range trees {
+		if nodeReferencesResource(tree.Root) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// nodeReferencesResource walks the supplied parse tree node looking for a
+// `.Resource` field
+func nodeReferencesResource(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if nodeReferencesResource(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeReferencesResource(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if nodeReferencesResource(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeReferencesResource(arg) {
+				return true
+			}
+		}
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == "Resource"
+	case *parse.ChainNode:
+		return nodeReferencesResource(n.Node)
+	case *parse.IfNode:
+		return nodeReferencesResource(&n.BranchNode)
+	case *parse.RangeNode:
+		return nodeReferencesResource(&n.BranchNode)
+	case *parse.WithNode:
+		return nodeReferencesResource(&n.BranchNode)
+	case *parse.BranchNode:
+		return nodeReferencesResource(n.Pipe) ||
+			nodeReferencesResource(n.List) ||
+			nodeReferencesResource(n.ElseList)
+	}
+	return false
+}
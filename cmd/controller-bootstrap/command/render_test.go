@@ -0,0 +1,457 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+// testTemplateVars returns the templateVars of a service with two resources
+func testTemplateVars() *templateVars {
+	return &templateVars{
+		metaVars: &metaVars{
+			ServiceID:          "ECR",
+			ServicePackageName: "ecr",
+			CRDNames:           []string{"Repository", "PullThroughCacheRule"},
+		},
+		CRDVersion: "v1alpha1",
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	data := map[string]string{"Name": "ecr", "Empty": "", "Up": ".."}
+	tests := []struct {
+		tplPath string
+		want    string
+		wantErr bool
+	}{
+		{"README.md.tpl", "README.md", false},
+		{"config/{{ .Name }}/kustomization.yaml.tpl", "config/ecr/kustomization.yaml", false},
+		{"{{ .Name }}.yaml", "ecr.yaml", false},
+		{"config/./{{ .Name }}/../samples.yaml.tpl", "config/samples.yaml", false},
+		{"config/{{ .Empty }}/kustomization.yaml.tpl", "", true},
+		{"{{ .Empty }}.tpl", "", true},
+		{"{{ .Up }}/{{ .Up }}/etc/passwd.tpl", "", true},
+		{"config/{{ .Up }}/{{ .Up }}/README.md.tpl", "", true},
+		{"{{ .Missing }}.tpl", "", true},
+		{"{{ .Name }.tpl", "", true},
+	}
+	for _, tt := range tests {
+		got, err := outputPath(tt.tplPath, data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("outputPath(%q) error = %v, wantErr %v", tt.tplPath, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("outputPath(%q) = %q, want %q", tt.tplPath, got, tt.want)
+		}
+	}
+}
+
+func TestReferencesResource(t *testing.T) {
+	tests := []struct {
+		tplPath string
+		want    bool
+	}{
+		{"README.md.tpl", false},
+		{"apis/{{.CRDVersion}}/doc.go.tpl", false},
+		{"test/e2e/resources/{{.Resource.Snake}}.yaml.tpl", true},
+		{"config/{{ lower .Resource.Name }}.yaml.tpl", true},
+		{"{{ if .ExistingController }}{{ .Resource.Kebab }}{{ end }}.tpl", true},
+		{"{{ with .Resource }}{{ .Snake }}{{ end }}.tpl", true},
+		{"{{ .ServicePackageName }}_{{ .Resource.Lower }}.yaml.tpl", true},
+	}
+	for _, tt := range tests {
+		got, err := referencesResource(tt.tplPath)
+		if err != nil {
+			t.Errorf("referencesResource(%q) unexpected error: %v", tt.tplPath, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("referencesResource(%q) = %v, want %v", tt.tplPath, got, tt.want)
+		}
+	}
+	if _, err := referencesResource("{{ .Resource.Snake }.tpl"); err == nil {
+		t.Error("expected an error for an invalid template path")
+	}
+}
+
+func TestRenderTargetsFanOut(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"README.md.tpl":                                   {Data: []byte("readme")},
+		"apis/{{.CRDVersion}}/doc.go.tpl":                 {Data: []byte("package {{ .CRDVersion }}")},
+		"test/e2e/resources/{{.Resource.Snake}}.yaml.tpl": {Data: []byte("kind: {{ .Resource.Name }}")},
+	})
+	targets, err := renderTargets(pack, testTemplateVars())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var files []string
+	for _, target := range targets {
+		files = append(files, target.file)
+	}
+	want := []string{
+		"README.md",
+		"apis/v1alpha1/doc.go",
+		"test/e2e/resources/repository.yaml",
+		"test/e2e/resources/pull_through_cache_rule.yaml",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got output files %v, want %v", files, want)
+	}
+	data, ok := targets[3].data.(*resourceTemplateVars)
+	if !ok || data.Resource.Name != "PullThroughCacheRule" {
+		t.Errorf("expected the per-resource target to be rendered with its resource, got %#v", targets[3].data)
+	}
+}
+
+func TestRenderTargetsPathErrors(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"README.md.tpl":                        {Data: []byte("readme")},
+		"{{ \"README.md\" }}.tpl":              {Data: []byte("duplicate")},
+		"{{ .ServiceModelName }}/escape.tpl":   {Data: []byte("empty segment")},
+		"config/{{ .Resource.Plural }}.go.tpl": {Data: []byte("{{ .Resource.Name }}")},
+		"config/{{ .Resource.Name }}s.go.tpl":  {Data: []byte("{{ .Resource.Name }}")},
+	})
+	targets, err := renderTargets(pack, testTemplateVars())
+	var errs templateErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected templateErrors, got %v", err)
+	}
+	got := map[string]int{}
+	for _, tplErr := range errs {
+		if tplErr.Phase != phasePath {
+			t.Errorf("%s: got phase %s, want %s", tplErr.File, tplErr.Phase, phasePath)
+		}
+		got[tplErr.File]++
+	}
+	want := map[string]int{
+		// the second template rendering to an existing output path fails
+		"{{ \"README.md\" }}.tpl":            1,
+		"{{ .ServiceModelName }}/escape.tpl": 1,
+		// only PullThroughCacheRules collides, Repositories does not
+		"config/{{ .Resource.Plural }}.go.tpl": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got errors %v, want %v", got, want)
+	}
+	if len(targets) != 4 {
+		t.Errorf("expected the targets without errors to be returned, got %d", len(targets))
+	}
+}
+
+// testDirPack returns a template pack of a local directory holding the
+// supplied files with the supplied permissions
+func testDirPack(t *testing.T, files map[string]os.FileMode, contents string) (*templatePack, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, perm := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), perm); err != nil {
+			t.Fatal(err)
+		}
+		// the permissions of the created file are subject to the umask
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newTemplatePack(), dir
+}
+
+func TestRenderTargetPermissions(t *testing.T) {
+	pack, dir := testDirPack(t, map[string]os.FileMode{
+		"README.md.tpl": 0644,
+		"build.sh.tpl":  0755,
+		"owner.sh.tpl":  0744,
+	}, "#!/bin/sh\n")
+	if err := os.WriteFile(filepath.Join(dir, "secret.tpl"), []byte("---\nperm: \"0600\"\n---\nsecret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.addLayer(dirTemplateLayer(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]os.FileMode{
+		"README.md.tpl": 0666,
+		// like git, only the executable bits of the template are honoured
+		"build.sh.tpl": 0777,
+		"owner.sh.tpl": 0777,
+		"secret.tpl":   0600,
+	}
+	var files []*renderedFile
+	for tplPath, perm := range want {
+		target, _, err := newRenderTarget(pack, tplPath)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tplPath, err)
+		}
+		if target.perm != perm {
+			t.Errorf("%s: got perm %o, want %o", tplPath, target.perm, perm)
+		}
+		target.file = tplPath[:len(tplPath)-len(templateSuffix)]
+		files = append(files, &renderedFile{renderTarget: target, contents: []byte(target.body)})
+	}
+
+	outDir := filepath.Join(t.TempDir(), "controller")
+	if err := writeOutput(context.Background(), outDir, files, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for file, executable := range map[string]bool{"README.md": false, "build.sh": true, "owner.sh": true} {
+		fi, err := os.Stat(filepath.Join(outDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode()&0100 != 0; got != executable {
+			t.Errorf("%s: got mode %v, want executable %v", file, fi.Mode(), executable)
+		}
+	}
+	fi, err := os.Stat(filepath.Join(outDir, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		t.Errorf("secret: got mode %v, want 0600", fi.Mode())
+	}
+}
+
+func TestRenderFileSkipsEmptyRenders(t *testing.T) {
+	partials := newTemplate(partialsDir)
+	for body, skipped := range map[string]bool{
+		"": true,
+		"{{ if .ExistingController }}x{{ end }}\n \n": true,
+		"{{ .ServiceID }}\n":                          false,
+	} {
+		target := &renderTarget{tplPath: "go.sum.tpl", file: "go.sum", body: body, data: testTemplateVars()}
+		result := renderFile(partials, target, t.TempDir())
+		if result.err != nil {
+			t.Fatalf("%q: unexpected error: %v", body, result.err)
+		}
+		if got := result.skipped != nil && result.skipped.Reason == skipReasonEmpty; got != skipped {
+			t.Errorf("%q: got skipped %v, want %v", body, got, skipped)
+		}
+		if !skipped && (result.file == nil || string(result.file.contents) != "ECR\n") {
+			t.Errorf("%q: expected the file to be rendered, got %+v", body, result)
+		}
+	}
+}
+
+func TestRenderTargetSymlinks(t *testing.T) {
+	pack, dir := testDirPack(t, map[string]os.FileMode{"LICENSE.tpl": 0644}, "license\n")
+	links := map[string]string{
+		"docs/LICENSE.tpl": "../LICENSE",
+		"COPYING.tpl":      "LICENSE",
+		"passwd.tpl":       "/etc/passwd",
+		"escape.tpl":       "docs/../../outside",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, dest := range links {
+		if err := os.Symlink(dest, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pack.addLayer(dirTemplateLayer(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, wantErr := range map[string]bool{
+		"COPYING.tpl":      false,
+		"docs/LICENSE.tpl": true,
+		"passwd.tpl":       true,
+		"escape.tpl":       true,
+	} {
+		target, _, err := newRenderTarget(pack, name)
+		if (err != nil) != wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", name, err, wantErr)
+			continue
+		}
+		if wantErr {
+			continue
+		}
+		if target.symlink != links[name] {
+			t.Errorf("%s: got symlink to %q, want %q", name, target.symlink, links[name])
+		}
+		target.file = "COPYING"
+		outDir := t.TempDir()
+		if err = writeOutput(context.Background(), outDir, []*renderedFile{{renderTarget: target}}, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dest, err := os.Readlink(filepath.Join(outDir, "COPYING")); err != nil || dest != "LICENSE" {
+			t.Errorf("got symlink to %q (%v), want LICENSE", dest, err)
+		}
+	}
+}
+
+func TestRenderFilesWorkers(t *testing.T) {
+	files := fstest.MapFS{
+		"_partials/header.tpl":                    {Data: []byte("# {{ .ServiceID }}\n")},
+		"README.md.tpl":                           {Data: []byte("{{ template \"header\" . }}\n")},
+		"go.sum.tpl":                              {Data: []byte("{{ range .GoSum }}{{ . }}{{ end }}")},
+		"broken.tpl":                              {Data: []byte("{{ .ServiceID.Missing }}")},
+		"{{.Resource.Snake}}/doc.go.tpl":          {Data: []byte("package {{ .Resource.Lower }}\n")},
+		"test/{{.Resource.Snake}}_test.py.tpl":    {Data: []byte("# {{ .Resource.Name }}\n")},
+		"test/{{.Resource.Snake}}/broken.yml.tpl": {Data: []byte("{{ .Resource.Missing }}")},
+	}
+	tplVars := testTemplateVars()
+	tplVars.CRDNames = []string{"Repository", "PullThroughCacheRule", "Registry", "Image", "Layer"}
+
+	type output struct {
+		Files   []string
+		Skipped []string
+		Errors  []string
+	}
+	render := func(workers int) output {
+		pack := lintTestPack(t, files)
+		partials, err := pack.parsePartials()
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets, err := renderTargets(pack, tplVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, skipped, err := renderFiles(context.Background(), partials, targets, t.TempDir(), workers)
+		var errs templateErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected templateErrors, got %v", err)
+		}
+		var out output
+		for _, f := range rendered {
+			out.Files = append(out.Files, f.file+": "+string(f.contents))
+		}
+		for _, fr := range skipped {
+			out.Skipped = append(out.Skipped, fr.Path)
+		}
+		for _, tplErr := range errs {
+			out.Errors = append(out.Errors, tplErr.File)
+		}
+		return out
+	}
+
+	want := render(1)
+	if len(want.Files) != 11 || len(want.Skipped) != 1 || len(want.Errors) != 6 {
+		t.Fatalf("unexpected output with a single worker: %+v", want)
+	}
+	for _, workers := range []int{2, 8, 0} {
+		for i := 0; i < 5; i++ {
+			if got := render(workers); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%d workers: got %+v, want %+v", workers, got, want)
+			}
+		}
+	}
+}
+
+// cancellingVars counts the templates rendered with it and cancels the
+// rendering when the template at cancelAt is rendered
+type cancellingVars struct {
+	rendered *int32
+	cancelAt int32
+	cancel   context.CancelFunc
+}
+
+// Render is called by the template body
+func (v *cancellingVars) Render() string {
+	if atomic.AddInt32(v.rendered, 1) == v.cancelAt {
+		v.cancel()
+	}
+	return "rendered"
+}
+
+func TestRenderFilesCancelled(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var rendered int32
+		data := &cancellingVars{rendered: &rendered, cancelAt: 3, cancel: cancel}
+		var targets []*renderTarget
+		for i := 0; i < 100; i++ {
+			targets = append(targets, &renderTarget{
+				tplPath: "file.tpl",
+				body:    "{{ .Render }}",
+				data:    data,
+			})
+		}
+		_, _, err := renderFiles(ctx, newTemplate(partialsDir), targets, t.TempDir(), workers)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), "interrupted") {
+			t.Errorf("%d workers: expected an interrupted error, got %v", workers, err)
+		}
+		// only the targets already being rendered finish after cancelling
+		if got := atomic.LoadInt32(&rendered); got > int32(2+workers) {
+			t.Errorf("%d workers: rendered %d templates after cancelling at the third", workers, got)
+		}
+	}
+}
+
+func TestMakefileTemplate(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(base, "Makefile.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := lintTestPack(t, fstest.MapFS{"Makefile.tpl": {Data: data}})
+	tplVars := testTemplateVars()
+	tplVars.ModulePath = "github.com/example/ecr-controller"
+	tplVars.RuntimeVersion = "v0.19.0"
+	targets, err := renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, _, err := renderFiles(context.Background(), newTemplate(""), targets, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rendered) != 1 || rendered[0].file != "Makefile" {
+		t.Fatalf("expected a single Makefile, got %+v", rendered)
+	}
+
+	contents := string(rendered[0].contents)
+	for _, want := range []string{
+		"SERVICE=ecr\n",
+		"CONTROLLER_MODULE=github.com/example/ecr-controller\n",
+		"ACK_RUNTIME_VERSION ?= v0.19.0\n",
+	} {
+		if !strings.Contains(contents, want) {
+			t.Errorf("expected %q in the Makefile\n%s", want, contents)
+		}
+	}
+	// every target is declared phony, as none of them produces a file of
+	// its name
+	decl := contents[strings.Index(contents, ".PHONY:"):]
+	decl = decl[:strings.Index(decl, "\n\n")]
+	phony := strings.Fields(strings.ReplaceAll(decl, "\\", ""))[1:]
+	for _, target := range []string{"build-controller", "test", "kind-test", "build-bundle", "helm-lint", "release"} {
+		if !strings.Contains(contents, "\n"+target+":") {
+			t.Errorf("expected a %s target in the Makefile\n%s", target, contents)
+		}
+		found := false
+		for _, name := range phony {
+			found = found || name == target
+		}
+		if !found {
+			t.Errorf("expected %s to be declared phony, got %v", target, phony)
+		}
+	}
+}
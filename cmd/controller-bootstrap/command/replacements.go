@@ -0,0 +1,109 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// e2eResourcesDir is the directory of the e2e test resource manifests,
+// relative to the output directory
+const e2eResourcesDir = "test/e2e/resources"
+
+// placeholderRegexp matches the $REPLACEMENT markers of resource manifests
+var placeholderRegexp = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
+
+// replacementValue is a value of the generated_replacement_values.py of the
+// e2e tests
+type replacementValue struct {
+	// Name is the placeholder name, e.g. "REPOSITORY_NAME"
+	Name string `json:"name"`
+	// Value is the Python expression of the value, either a string literal,
+	// a random resource name or an attribute of a bootstrap resource. It
+	// is evaluated when a test runs rather than when the module is imported.
+	Value string `json:"value"`
+}
+
+// e2ePlaceholders returns the placeholders of the e2e tests, mapped to the
+// shape members they stand for, which are empty if unknown. These are the
+// placeholders of the sample specs and names of the supplied custom
+// resources, and of the resource manifests already in the output directory,
+// which keeps the values of the custom resources generated by earlier runs.
+func e2ePlaceholders(models map[string]*crdModel, crdNames []string, outDir string) map[string]string {
+	placeholders := map[string]string{}
+	for _, crdName := range crdNames {
+		placeholders[strings.ToUpper(toSnakeCase(crdName))+"_NAME"] = ""
+		if model, ok := models[crdName]; ok {
+			for name, memberName := range model.placeholders {
+				placeholders[name] = memberName
+			}
+		}
+	}
+	for _, name := range manifestPlaceholders(filepath.Join(outDir, filepath.FromSlash(e2eResourcesDir))) {
+		if _, ok := placeholders[name]; !ok {
+			// the words of the placeholder stand for the member, e.g.
+			// "KMS_KEY_ID" for "KmsKeyId"
+			placeholders[name] = strings.ReplaceAll(name, "_", "")
+		}
+	}
+	return placeholders
+}
+
+// getReplacementValues returns the replacement values of the supplied
+// placeholders, see e2ePlaceholders
+func getReplacementValues(serviceAlias string, placeholders map[string]string) []*replacementValue {
+	values := make([]*replacementValue, 0, len(placeholders))
+	for name, memberName := range placeholders {
+		value := strconv.Quote("ack-test-" + strings.ReplaceAll(strings.ToLower(name), "_", "-"))
+		if ref := findBootstrapReference(serviceAlias, memberName); memberName != "" && ref != nil {
+			value = "get_bootstrap_resources()." + ref.resource.Name + "." + ref.attribute
+		} else if name == "NAME" || strings.HasSuffix(name, "_NAME") {
+			// resource names must not collide across test runs
+			value = "random_suffix_name(" + value + ", 32)"
+		}
+		values = append(values, &replacementValue{Name: name, Value: value})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	return values
+}
+
+// manifestPlaceholders returns the placeholders used by the YAML manifests
+// of the supplied directory, which may not exist
+func manifestPlaceholders(dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, m := range placeholderRegexp.FindAllStringSubmatch(string(data), -1) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
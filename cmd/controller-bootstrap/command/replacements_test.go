@@ -0,0 +1,198 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGetReplacementValues(t *testing.T) {
+	outDir := t.TempDir()
+	resourcesDir := filepath.Join(outDir, filepath.FromSlash(e2eResourcesDir))
+	if err := os.MkdirAll(resourcesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "metadata:\n  name: $FUNCTION_NAME\nspec:\n  name: $NAME\n  kmsKeyARN: $KMS_KEY_ARN\n  code:\n    s3Key: $CODE_S3_KEY\n"
+	if err := ioutil.WriteFile(filepath.Join(resourcesDir, "function.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	models := map[string]*crdModel{
+		"Function": {placeholders: map[string]string{"ROLE": "Role", "FUNCTION_NAME": "FunctionName"}},
+	}
+
+	placeholders := e2ePlaceholders(models, []string{"Function", "Alias"}, outDir)
+	got := getReplacementValues("lambda", placeholders)
+	want := []*replacementValue{
+		{Name: "ALIAS_NAME", Value: `random_suffix_name("ack-test-alias-name", 32)`},
+		{Name: "CODE_S3_KEY", Value: `"ack-test-code-s3-key"`},
+		{Name: "FUNCTION_NAME", Value: `random_suffix_name("ack-test-function-name", 32)`},
+		{Name: "KMS_KEY_ARN", Value: "get_bootstrap_resources().ServiceKey.arn"},
+		{Name: "NAME", Value: `random_suffix_name("ack-test-name", 32)`},
+		{Name: "ROLE", Value: "get_bootstrap_resources().ServiceRole.arn"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, v := range got {
+			t.Logf("%+v", *v)
+		}
+		t.Errorf("unexpected replacement values")
+	}
+}
+
+// replacementTestPack returns a template pack of the embedded e2e resource,
+// replacement values and test templates
+func replacementTestPack(t *testing.T) *templatePack {
+	t.Helper()
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := fstest.MapFS{}
+	for _, path := range []string{
+		"_partials/header.py.tpl",
+		"_partials/resource.spec.yaml.tpl",
+		"test/e2e/generated_replacement_values.py.tpl",
+		"test/e2e/replacement_values.py.tpl",
+		"test/e2e/resources/{{.Resource.Snake}}.yaml.tpl",
+	} {
+		data, err := fs.ReadFile(base, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = &fstest.MapFile{Data: data}
+	}
+	return lintTestPack(t, files)
+}
+
+func TestReplacementValuesTemplate(t *testing.T) {
+	pack := replacementTestPack(t)
+	partials, err := pack.parsePartials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tplVars := testTemplateVars()
+	tplVars.ReplacementValues = []*replacementValue{
+		{Name: "CODE_S3_KEY", Value: `"ack-test-code-s3-key"`},
+		{Name: "FUNCTION_NAME", Value: `random_suffix_name("ack-test-function-name", 32)`},
+		{Name: "ROLE", Value: "get_bootstrap_resources().ServiceRole.arn"},
+	}
+	targets, err := renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, _, err := renderFiles(context.Background(), partials, targets, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := map[string]*renderedFile{}
+	for _, f := range rendered {
+		files[f.file] = f
+	}
+
+	generated := files["test/e2e/generated_replacement_values.py"]
+	if generated == nil || generated.mode != writeModeOverwrite {
+		t.Fatalf("expected generated_replacement_values.py to be overwritten, got %+v", generated)
+	}
+	want := "    return {\n" +
+		"        \"CODE_S3_KEY\": \"ack-test-code-s3-key\",\n" +
+		"        \"FUNCTION_NAME\": random_suffix_name(\"ack-test-function-name\", 32),\n" +
+		"        \"ROLE\": get_bootstrap_resources().ServiceRole.arn,\n" +
+		"    }\n"
+	if contents := string(generated.contents); !strings.Contains(contents, want) {
+		t.Errorf("expected the values\n%s\nin\n%s", want, contents)
+	}
+
+	overrides := files["test/e2e/replacement_values.py"]
+	if overrides == nil || overrides.mode != writeModeCreateOnly {
+		t.Fatalf("expected replacement_values.py to be create-only, got %+v", overrides)
+	}
+	if contents := string(overrides.contents); !strings.Contains(contents, "REPLACEMENT_VALUES = {\n}\n") {
+		t.Errorf("expected no overrides in\n%s", contents)
+	}
+}
+
+func TestReplacementValuesRegenerated(t *testing.T) {
+	pack := replacementTestPack(t)
+	defer func(output string, format string) {
+		optOutputPath, optOutputFormat = output, format
+	}(optOutputPath, optOutputFormat)
+	optOutputFormat = outputFormatText
+	optOutputPath = filepath.Join(t.TempDir(), "controller")
+
+	models := map[string]*crdModel{
+		"Repository": {
+			spec:         map[string]interface{}{"repositoryName": "$REPOSITORY_NAME"},
+			placeholders: map[string]string{"REPOSITORY_NAME": "RepositoryName"},
+		},
+		"PullThroughCacheRule": {
+			spec: map[string]interface{}{
+				"ecrRepositoryPrefix": "$ECR_REPOSITORY_PREFIX",
+				"upstreamRegistryURL": "$UPSTREAM_REGISTRY_URL",
+			},
+			placeholders: map[string]string{
+				"ECR_REPOSITORY_PREFIX": "EcrRepositoryPrefix",
+				"UPSTREAM_REGISTRY_URL": "UpstreamRegistryUrl",
+			},
+		},
+	}
+	// generate renders the templates for the supplied resources, like
+	// `controller-bootstrap generate --resource` does
+	generate := func(crdNames ...string) *generateReport {
+		tplVars := testTemplateVars()
+		tplVars.CRDNames = crdNames
+		tplVars.crdModels = models
+		tplVars.ReplacementValues = getReplacementValues("ecr", e2ePlaceholders(models, crdNames, optOutputPath))
+		report := newGenerateReport()
+		if err := generateFiles(context.Background(), &cobra.Command{}, report, pack, tplVars); err != nil {
+			t.Fatalf("unexpected error generating %v: %v", crdNames, err)
+		}
+		return report
+	}
+	generate("Repository")
+	if err := ioutil.WriteFile(filepath.Join(optOutputPath, "test", "e2e", "replacement_values.py"),
+		[]byte("REPLACEMENT_VALUES = {\"REPOSITORY_NAME\": \"mine\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := generate("PullThroughCacheRule")
+
+	for _, fr := range report.Files {
+		if fr.Path == "test/e2e/replacement_values.py" && fr.Reason != skipReasonExists {
+			t.Errorf("expected the user's replacement_values.py to be kept, got %+v", fr)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(optOutputPath, "test", "e2e", "generated_replacement_values.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every placeholder of the manifests of both runs has a value
+	contents := string(data)
+	names := manifestPlaceholders(filepath.Join(optOutputPath, filepath.FromSlash(e2eResourcesDir)))
+	if len(names) != 5 {
+		t.Errorf("expected the manifests of both runs, got placeholders %v", names)
+	}
+	for _, name := range names {
+		if !strings.Contains(contents, "\""+name+"\": ") {
+			t.Errorf("expected a value for %s in\n%s", name, contents)
+		}
+	}
+}
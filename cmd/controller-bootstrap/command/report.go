@@ -0,0 +1,265 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// outputFormatText is the human readable output of the commands
+	outputFormatText = "text"
+	// outputFormatJSON is the machine readable output of the commands
+	outputFormatJSON = "json"
+)
+
+// fileStatus describes what generating an output file did
+type fileStatus string
+
+const (
+	// fileCreated is a file which did not exist in the output directory
+	fileCreated fileStatus = "created"
+	// fileChanged is an existing file whose contents changed
+	fileChanged fileStatus = "changed"
+	// fileUnchanged is an existing file whose contents did not change
+	fileUnchanged fileStatus = "unchanged"
+	// fileSkipped is a file which was not written, see fileReport.Reason
+	fileSkipped fileStatus = "skipped"
+)
+
+// generateReport is the JSON output of the generate command
+type generateReport struct {
+	Service            *metaVars `json:"service"`
+	APIVersion         string    `json:"apiVersion"`
+	AWSSDKGoVersion    string    `json:"awsSDKGoVersion"`
+	RuntimeVersion     string    `json:"runtimeVersion"`
+	TestInfraCommitSHA string    `json:"testInfraCommitSHA"`
+	Output             string    `json:"output"`
+	DryRun             bool      `json:"dryRun"`
+	// Written is true when the output directory was written, which with
+	// --keep-going may lack the files that failed. It is false when no
+	// file was written, e.g. in dry-run mode or after a rollback.
+	Written bool          `json:"written"`
+	Files   []*fileReport `json:"files"`
+	Errors  []errorReport `json:"errors"`
+}
+
+// fileReport describes a single output file
+type fileReport struct {
+	Path     string     `json:"path"`
+	Template string     `json:"template"`
+	Status   fileStatus `json:"status"`
+	// Reason is the reason a file was skipped
+	Reason string `json:"reason,omitempty"`
+	// Contents is the rendered file, only reported in dry-run mode
+	Contents *string `json:"contents,omitempty"`
+	// Symlink is the destination of a symlink
+	Symlink string `json:"symlink,omitempty"`
+}
+
+// errorReport describes an error. File and Phase are empty for errors
+// which are not specific to a template file.
+type errorReport struct {
+	File    string `json:"file,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message"`
+}
+
+// lintReport is the JSON output of the lint-templates command
+type lintReport struct {
+	Issues []lintIssueReport `json:"issues"`
+	// Errors are the errors which prevented the templates from being linted
+	Errors []errorReport `json:"errors"`
+}
+
+// commandReport is the JSON output of a command without a report of its
+// own, e.g. when no subcommand is run
+type commandReport struct {
+	Errors []errorReport `json:"errors"`
+}
+
+// reportedError is an error a command already added to its JSON report
+type reportedError struct {
+	error
+}
+
+// Unwrap returns the underlying error
+func (e *reportedError) Unwrap() error {
+	return e.error
+}
+
+// lintIssueReport describes a single lintIssue
+type lintIssueReport struct {
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+	Warning  bool   `json:"warning"`
+}
+
+// newGenerateReport returns an empty generateReport for the supplied flags
+func newGenerateReport() *generateReport {
+	return &generateReport{
+		AWSSDKGoVersion:    optAWSSDKGoVersion,
+		RuntimeVersion:     optRuntimeVersion,
+		TestInfraCommitSHA: optTestInfraCommitSHA,
+		Output:             optOutputPath,
+		DryRun:             optDryRun,
+		Files:              []*fileReport{},
+		Errors:             []errorReport{},
+	}
+}
+
+// newFailureReport returns the JSON report of the supplied command when it
+// fails before writing a report itself, e.g. on invalid or missing flags
+func newFailureReport(cmd *cobra.Command, err error) interface{} {
+	switch cmd {
+	case templateCmd:
+		report := newGenerateReport()
+		report.addError(err)
+		return report
+	case lintCmd:
+		return &lintReport{
+			Issues: []lintIssueReport{},
+			Errors: []errorReport{{Message: err.Error()}},
+		}
+	}
+	return &commandReport{Errors: []errorReport{{Message: err.Error()}}}
+}
+
+// addError adds the supplied error to the report, expanding templateErrors
+// into one errorReport per file
+func (r *generateReport) addError(err error) {
+	var tplErrs templateErrors
+	if errors.As(err, &tplErrs) {
+		for _, tplErr := range tplErrs {
+			r.addError(tplErr)
+		}
+		return
+	}
+	var tplErr *templateError
+	if errors.As(err, &tplErr) {
+		r.Errors = append(r.Errors, errorReport{
+			File:    tplErr.File,
+			Phase:   tplErr.Phase,
+			Message: tplErr.Message(),
+		})
+		return
+	}
+	r.Errors = append(r.Errors, errorReport{Message: err.Error()})
+}
+
+// addFile adds the supplied rendered file to the report, comparing it to
+// the file of the same path in the output directory
+func (r *generateReport) addFile(f *renderedFile, outDir string) {
+	fr := &fileReport{
+		Path:     f.file,
+		Template: f.tplPath,
+		Status:   renderedFileStatus(f, outDir),
+		Symlink:  f.symlink,
+	}
+	if r.DryRun && f.symlink == "" {
+		contents := string(f.contents)
+		fr.Contents = &contents
+	}
+	r.Files = append(r.Files, fr)
+}
+
+// markNotWritten marks the files of the report as skipped, when errors
+// prevented the output directory from being written
+func (r *generateReport) markNotWritten() {
+	for _, fr := range r.Files {
+		if fr.Status != fileSkipped {
+			fr.Status = fileSkipped
+			fr.Reason = "not written due to errors"
+		}
+	}
+}
+
+// markWriteFailed marks the files which failed to be written as skipped
+func (r *generateReport) markWriteFailed(errs templateErrors) {
+	failed := map[string]bool{}
+	for _, err := range errs {
+		failed[err.File] = true
+	}
+	for _, fr := range r.Files {
+		if failed[fr.Path] {
+			fr.Status = fileSkipped
+			fr.Reason = "write failed"
+		}
+	}
+}
+
+// renderedFileStatus returns whether writing the supplied rendered file
+// creates, changes or leaves unchanged the file in the output directory
+func renderedFileStatus(f *renderedFile, outDir string) fileStatus {
+	outPath := filepath.Join(outDir, filepath.FromSlash(f.file))
+	if f.symlink != "" {
+		dest, err := os.Readlink(outPath)
+		switch {
+		case err == nil && dest == f.symlink:
+			return fileUnchanged
+		case err == nil:
+			return fileChanged
+		}
+		if _, err = os.Lstat(outPath); err == nil {
+			return fileChanged
+		}
+		return fileCreated
+	}
+	existing, err := ioutil.ReadFile(outPath)
+	switch {
+	case err != nil && os.IsNotExist(err):
+		return fileCreated
+	case err != nil:
+		return fileChanged
+	case f.mode == writeModeAppend:
+		return fileChanged
+	case bytes.Equal(existing, f.contents):
+		return fileUnchanged
+	}
+	return fileChanged
+}
+
+// isJSONOutput returns true if the commands emit JSON
+func isJSONOutput() bool {
+	return optOutputFormat == outputFormatJSON
+}
+
+// validateOutputFormat returns an error for an unsupported --output-format
+func validateOutputFormat() error {
+	switch optOutputFormat {
+	case outputFormatText, outputFormatJSON:
+		return nil
+	}
+	return fmt.Errorf(
+		"invalid --output-format %q, expected %s or %s",
+		optOutputFormat, outputFormatText, outputFormatJSON,
+	)
+}
+
+// printJSON writes the supplied value as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,167 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+)
+
+// decodeReport decodes the single JSON document written to stdout
+func decodeReport(t *testing.T, stdout string, v interface{}) {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("cannot decode the JSON report: %v\n%s", err, stdout)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Fatalf("expected a single JSON document on stdout:\n%s", stdout)
+	}
+}
+
+func TestJSONReportEarlyFailures(t *testing.T) {
+	defer resetFlags(rootCmd)
+	defer rootCmd.SetArgs(nil)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			"invalid flag value",
+			[]string{"generate", "-s", "ecr", "-r", "v0.19.0", "-v", "v1.44.25", "-o", "out", "-t", "main"},
+			"invalid --test-infra-commit-sha",
+		},
+		{
+			"missing required flags",
+			[]string{"generate", "-s", "ecr"},
+			"required flag(s)",
+		},
+		{
+			"unreadable config file",
+			[]string{"generate", "--config", filepath.Join(t.TempDir(), "missing.yaml")},
+			"cannot read config file",
+		},
+	}
+	for _, tt := range tests {
+		resetFlags(rootCmd)
+		var stdout, stderr bytes.Buffer
+		rootCmd.SetOut(&stdout)
+		rootCmd.SetErr(&stderr)
+		rootCmd.SetArgs(append(tt.args, "--output-format", "json"))
+		err := execute()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		var report generateReport
+		decodeReport(t, stdout.String(), &report)
+		if len(report.Errors) != 1 || report.Errors[0].Message != err.Error() {
+			t.Errorf("%s: got errors %v in the report, want %q", tt.name, report.Errors, err)
+		}
+		if report.Files == nil {
+			t.Errorf("%s: expected an empty list of files", tt.name)
+		}
+	}
+}
+
+func TestJSONReportLintFailure(t *testing.T) {
+	defer resetFlags(rootCmd)
+	defer rootCmd.SetArgs(nil)
+
+	resetFlags(rootCmd)
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	missing := filepath.Join(t.TempDir(), "missing")
+	rootCmd.SetArgs([]string{"lint-templates", "--template-dir", missing, "--output-format", "json"})
+	err := execute()
+	if err == nil {
+		t.Fatal("expected a missing template directory to fail")
+	}
+
+	var report lintReport
+	decodeReport(t, stdout.String(), &report)
+	if len(report.Errors) != 1 || report.Errors[0].Message != err.Error() {
+		t.Errorf("got errors %v in the report, want %q", report.Errors, err)
+	}
+}
+
+func TestJSONReportGenerate(t *testing.T) {
+	readme := &fstest.MapFile{Data: []byte("# {{ .ServiceID }}\n")}
+	defer func(output string, dryRun bool, format string) {
+		optOutputPath, optDryRun, optOutputFormat = output, dryRun, format
+	}(optOutputPath, optDryRun, optOutputFormat)
+	optOutputPath = filepath.Join(t.TempDir(), "controller")
+	optDryRun = true
+	optOutputFormat = outputFormatJSON
+
+	// A successful run reports every file
+	cmd := &cobra.Command{}
+	cmd.SetErr(io.Discard)
+	report := newGenerateReport()
+	pack := lintTestPack(t, fstest.MapFS{"README.md.tpl": readme})
+	if err := generateFiles(context.Background(), cmd, report, pack, testTemplateVars()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stdout bytes.Buffer
+	if err := printJSON(&stdout, report); err != nil {
+		t.Fatal(err)
+	}
+	var got generateReport
+	decodeReport(t, stdout.String(), &got)
+	if len(got.Errors) != 0 {
+		t.Errorf("unexpected errors %v", got.Errors)
+	}
+	if len(got.Files) != 1 {
+		t.Fatalf("expected a single file, got %v", got.Files)
+	}
+	f := got.Files[0]
+	if f.Path != "README.md" || f.Template != "README.md.tpl" || f.Status != fileCreated {
+		t.Errorf("got file %+v, want README.md created from README.md.tpl", f)
+	}
+	if f.Contents == nil || *f.Contents != "# ECR\n" {
+		t.Errorf("got contents %v, want the rendered README.md", f.Contents)
+	}
+
+	// A failed run reports the file which could not be generated
+	pack = lintTestPack(t, fstest.MapFS{
+		"README.md.tpl": readme,
+		"OWNERS.tpl":    {Data: []byte("{{ .ServiceID.Owners }}\n")},
+	})
+	report = newGenerateReport()
+	if err := generateFiles(context.Background(), cmd, report, pack, testTemplateVars()); err == nil {
+		t.Fatal("expected OWNERS.tpl to fail")
+	}
+	stdout.Reset()
+	if err := printJSON(&stdout, report); err != nil {
+		t.Fatal(err)
+	}
+	got = generateReport{}
+	decodeReport(t, stdout.String(), &got)
+	if len(got.Errors) != 1 || got.Errors[0].File != "OWNERS.tpl" || got.Errors[0].Phase != "execute" {
+		t.Errorf("got errors %+v, want an execute error of OWNERS.tpl", got.Errors)
+	}
+}
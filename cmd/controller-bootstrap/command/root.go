@@ -14,6 +14,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -26,14 +27,31 @@ const (
 )
 
 var (
-	optServiceAlias       string
-	optRuntimeVersion     string
-	optAWSSDKGoVersion    string
-	optDryRun             bool
-	optExistingController bool
-	optOutputPath         string
-	optModelName          string
-	optTestInfraCommitSHA string
+	optServiceAlias            string
+	optRuntimeVersion          string
+	optAWSSDKGoVersion         string
+	optDryRun                  bool
+	optExistingController      bool
+	optOutputPath              string
+	optModelName               string
+	optTestInfraCommitSHA      string
+	optTemplateDir             string
+	optTemplateOverlays        []string
+	optRenderJobs              int
+	optKeepGoing               bool
+	optOutputFormat            string
+	optResources               []string
+	optConfigPath              string
+	optRuntimeRepoURL          string
+	optTestInfraPath           string
+	optRuntimePath             string
+	optGoSum                   bool
+	optModulePath              string
+	optGoVersion               string
+	optCRDVersion              string
+	optAPIStatus               string
+	optServiceLink             string
+	optServiceDocumentationURL string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -41,47 +59,55 @@ var (
 var rootCmd = &cobra.Command{
 	Use:   appName,
 	Short: appShortDesc,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		// The usage would be mixed up with the JSON report
+		cmd.SilenceUsage = cmd.SilenceUsage || isJSONOutput()
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(
-		&optServiceAlias, "aws-service-alias", "", "AWS service alias",
+		&optConfigPath, "config", "", "Optional: path to a bootstrap.yaml configuration file, defaults to the one in the output directory",
 	)
 	rootCmd.PersistentFlags().StringVar(
-		&optRuntimeVersion, "ack-runtime-version", "", "Version of aws-controllers-k8s/runtime",
+		&optTemplateDir, "template-dir", "", "Optional: path to a template directory used instead of the embedded templates",
 	)
-	rootCmd.PersistentFlags().StringVar(
-		&optAWSSDKGoVersion, "aws-sdk-go-version", "", "Version of github.com/aws/aws-sdk-go used to infer service metadata and resources",
-	)
-	rootCmd.PersistentFlags().BoolVar(
-		&optDryRun, "dry-run", false, "Optional: if true, output files to stdout",
-	)
-	rootCmd.PersistentFlags().BoolVar(
-		&optExistingController, "existing-controller", false, "Optional: if true, update the existing service controller",
+	rootCmd.PersistentFlags().StringArrayVar(
+		&optTemplateOverlays, "template-overlay", nil, "Optional: directory or git repository URL[@ref] of a template overlay applied on top of the base templates, may be repeated",
 	)
 	rootCmd.PersistentFlags().StringVar(
-		&optOutputPath, "output", "", "Path to ACK service controller directory to bootstrap",
+		&optOutputFormat, "output-format", outputFormatText, "Optional: output format of the commands, either text or json",
 	)
-	rootCmd.PersistentFlags().StringVar(
-		&optModelName, "model-name", "", "Optional: service model name of the corresponding service alias",
-	)
-	rootCmd.PersistentFlags().StringVar(
-		&optTestInfraCommitSHA, "test-infra-commit-sha", "", "Commit SHA of aws-controllers-k8s/test-infra",
-	)
-	rootCmd.MarkPersistentFlagRequired("aws-service-alias")
-	rootCmd.MarkPersistentFlagRequired("ack-runtime-version")
-	rootCmd.MarkPersistentFlagRequired("aws-sdk-go-version")
-	rootCmd.MarkPersistentFlagRequired("output")
-	rootCmd.MarkPersistentFlagRequired("test-infra-commit-sha")
 	rootCmd.AddCommand(templateCmd)
+	rootCmd.AddCommand(lintCmd)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
+	if err := execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+// execute runs the root command. With JSON output, a command which fails
+// before writing its report, e.g. on invalid flags, reports the error.
+func execute() error {
+	cmd, err := rootCmd.ExecuteC()
+	var reported *reportedError
+	if err == nil || errors.As(err, &reported) || !isJSONOutput() {
+		return err
+	}
+	if jsonErr := printJSON(cmd.OutOrStdout(), newFailureReport(cmd, err)); jsonErr != nil {
+		return jsonErr
+	}
+	return err
+}
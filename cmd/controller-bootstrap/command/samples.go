@@ -0,0 +1,253 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"strings"
+
+	awssdkmodel "github.com/aws/aws-sdk-go/private/model/api"
+)
+
+// maxSampleDepth bounds the nesting of sample specs, as shapes may be
+// recursive
+const maxSampleDepth = 5
+
+// crdModel is what the service model tells about a custom resource
+type crdModel struct {
+	// spec is a sample spec, see resourceVars.Spec
+	spec map[string]interface{}
+	// read is the operation describing the resource, see resourceVars.Read
+	read *readOperation
+	// placeholders maps the names of the spec's $REPLACEMENT markers to
+	// the shape members they stand for
+	placeholders map[string]string
+}
+
+// readOperation is the API operation describing a resource
+type readOperation struct {
+	// Name is the operation name, e.g. "DescribeRepositories"
+	Name string
+	// Params are the input members identifying the resource
+	Params []readParam
+	// List is true for a List<Names> operation, which returns no resources
+	// instead of an error once the resource is deleted
+	List bool
+	// Result is the output member listing the resources of a List
+	// operation, empty if the output has no list member
+	Result string
+	// Filtered is true if a parameter identifies the resource by one of
+	// its spec fields, so that a List operation lists only the resource
+	Filtered bool
+}
+
+// readParam is an input member of a readOperation
+type readParam struct {
+	// Member is the input member name, e.g. "RepositoryNames"
+	Member string
+	// Field is the spec field holding the member's value, empty if the
+	// spec has no such field
+	Field string
+	// List is true if the member is a list of the Field's values
+	List bool
+}
+
+// getCRDModels returns the models of the supplied custom resources, keyed
+// by resource name
+func getCRDModels(api *awssdkmodel.API, crdNames []string) map[string]*crdModel {
+	models := map[string]*crdModel{}
+	for _, crdName := range crdNames {
+		op, ok := api.Operations["Create"+crdName]
+		if !ok || op.InputRef.Shape == nil {
+			continue
+		}
+		placeholders := map[string]string{}
+		models[crdName] = &crdModel{
+			spec:         sampleStructure(op.InputRef.Shape, 0, placeholders),
+			read:         findReadOperation(api, crdName, op.InputRef.Shape),
+			placeholders: placeholders,
+		}
+	}
+	return models
+}
+
+// findReadOperation returns the operation describing a single resource,
+// which is the first one of Describe<Name>, Get<Name>, Describe<Names> and
+// List<Names> that the service has. The operation's parameters are matched
+// to the required members of the create input shape.
+func findReadOperation(api *awssdkmodel.API, crdName string, createInput *awssdkmodel.Shape) *readOperation {
+	plural := pluralizer.Plural(crdName)
+	for _, name := range []string{"Describe" + crdName, "Get" + crdName, "Describe" + plural, "List" + plural} {
+		op, ok := api.Operations[name]
+		if !ok {
+			continue
+		}
+		read := &readOperation{Name: name}
+		if strings.HasPrefix(name, "List") {
+			read.List = true
+			read.Result = listResultMember(op.OutputRef.Shape, plural)
+		}
+		input := op.InputRef.Shape
+		if input == nil {
+			return read
+		}
+		matched := map[string]bool{}
+		for _, member := range createInput.Required {
+			switch {
+			case input.MemberRefs[member] != nil:
+				read.Params = append(read.Params, readParam{Member: member, Field: crdFieldName(member)})
+				matched[member] = true
+				read.Filtered = true
+			case isListMember(input, pluralizer.Plural(member)):
+				read.Params = append(read.Params, readParam{Member: pluralizer.Plural(member), Field: crdFieldName(member), List: true})
+				matched[pluralizer.Plural(member)] = true
+				read.Filtered = true
+			}
+		}
+		for _, member := range input.Required {
+			if !matched[member] {
+				read.Params = append(read.Params, readParam{Member: member})
+			}
+		}
+		return read
+	}
+	return nil
+}
+
+// listResultMember returns the member of a List operation's output shape
+// listing the resources, which is the plural of the resource name or else
+// the first list member
+func listResultMember(output *awssdkmodel.Shape, plural string) string {
+	if output == nil {
+		return ""
+	}
+	if isListMember(output, plural) {
+		return plural
+	}
+	for _, member := range output.MemberNames() {
+		if isListMember(output, member) {
+			return member
+		}
+	}
+	return ""
+}
+
+// isListMember returns true if the shape has a list member of that name
+func isListMember(shape *awssdkmodel.Shape, member string) bool {
+	ref, ok := shape.MemberRefs[member]
+	return ok && ref.Shape != nil && ref.Shape.Type == "list"
+}
+
+// sampleStructure returns the required members of a structure shape with
+// placeholder values, keyed by their custom resource field names. The
+// placeholders used are added to the supplied map.
+func sampleStructure(shape *awssdkmodel.Shape, depth int, placeholders map[string]string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for _, memberName := range shape.Required {
+		ref, ok := shape.MemberRefs[memberName]
+		if !ok || ref.Shape == nil {
+			continue
+		}
+		fields[crdFieldName(memberName)] = sampleValue(memberName, ref.Shape, depth+1, placeholders)
+	}
+	return fields
+}
+
+// sampleValue returns a placeholder value of the supplied shape. Strings
+// are $REPLACEMENT markers named after the member, e.g. $REPOSITORY_NAME,
+// which the e2e tests substitute.
+func sampleValue(memberName string, shape *awssdkmodel.Shape, depth int, placeholders map[string]string) interface{} {
+	if depth > maxSampleDepth {
+		return nil
+	}
+	switch shape.Type {
+	case "structure":
+		return sampleStructure(shape, depth, placeholders)
+	case "list":
+		if shape.MemberRef.Shape == nil {
+			return []interface{}{}
+		}
+		return []interface{}{sampleValue(pluralizer.Singular(memberName), shape.MemberRef.Shape, depth+1, placeholders)}
+	case "map":
+		if shape.ValueRef.Shape == nil {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"key": sampleValue(memberName, shape.ValueRef.Shape, depth+1, placeholders)}
+	case "boolean":
+		return false
+	case "integer", "long":
+		if shape.Min > 0 {
+			return int64(shape.Min)
+		}
+		return 1
+	case "double", "float":
+		if shape.Min > 0 {
+			return shape.Min
+		}
+		return 1.0
+	case "timestamp":
+		return "2006-01-02T15:04:05Z"
+	}
+	if len(shape.Enum) > 0 {
+		return shape.Enum[0]
+	}
+	placeholder := strings.ToUpper(toSnakeCase(memberName))
+	placeholders[placeholder] = memberName
+	return "$" + placeholder
+}
+
+// exampleSpec returns a copy of the supplied sample spec whose placeholders
+// are replaced by example values, e.g. "$REPOSITORY_NAME" by
+// "my-repository-name", for the specs shown to users rather than loaded
+// by the e2e tests
+func exampleSpec(spec map[string]interface{}) map[string]interface{} {
+	if spec == nil {
+		return nil
+	}
+	return exampleValue(spec).(map[string]interface{})
+}
+
+// exampleValue replaces the placeholders of a sample value, see exampleSpec
+func exampleValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		example := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			example[key] = exampleValue(value)
+		}
+		return example
+	case []interface{}:
+		example := make([]interface{}, len(v))
+		for i, value := range v {
+			example[i] = exampleValue(value)
+		}
+		return example
+	case string:
+		if m := placeholderRegexp.FindStringSubmatch(v); m != nil && m[0] == v {
+			return "my-" + strings.ReplaceAll(strings.ToLower(m[1]), "_", "-")
+		}
+	}
+	return v
+}
+
+// crdFieldName returns the custom resource field name of a shape member,
+// which lowercases the first word and keeps the case of the others,
+// e.g. "KMSKeyID" becomes "kmsKeyID"
+func crdFieldName(memberName string) string {
+	words := splitWords(memberName)
+	if len(words) == 0 {
+		return memberName
+	}
+	i := strings.Index(memberName, words[0])
+	return strings.ToLower(memberName[:i+len(words[0])]) + memberName[i+len(words[0]):]
+}
@@ -0,0 +1,331 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	awssdkmodel "github.com/aws/aws-sdk-go/private/model/api"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCRDFieldName(t *testing.T) {
+	cases := map[string]string{
+		"RepositoryName": "repositoryName",
+		"KMSKeyID":       "kmsKeyID",
+		"DBInstanceARN":  "dbInstanceARN",
+		"Bucket":         "bucket",
+	}
+	for member, want := range cases {
+		if got := crdFieldName(member); got != want {
+			t.Errorf("crdFieldName(%q) = %q, want %q", member, got, want)
+		}
+	}
+}
+
+func TestSampleStructure(t *testing.T) {
+	str := &awssdkmodel.Shape{Type: "string"}
+	tag := &awssdkmodel.Shape{
+		Type:     "structure",
+		Required: []string{"Key"},
+		MemberRefs: map[string]*awssdkmodel.ShapeRef{
+			"Key":   {Shape: str},
+			"Value": {Shape: str},
+		},
+	}
+	input := &awssdkmodel.Shape{
+		Type:     "structure",
+		Required: []string{"RepositoryName", "ImageTagMutability", "MaxImages", "ScanOnPush", "Tags", "Labels"},
+		MemberRefs: map[string]*awssdkmodel.ShapeRef{
+			"RepositoryName":     {Shape: str},
+			"ImageTagMutability": {Shape: &awssdkmodel.Shape{Type: "string", Enum: []string{"MUTABLE", "IMMUTABLE"}}},
+			"MaxImages":          {Shape: &awssdkmodel.Shape{Type: "integer", Min: 10}},
+			"ScanOnPush":         {Shape: &awssdkmodel.Shape{Type: "boolean"}},
+			"Tags":               {Shape: &awssdkmodel.Shape{Type: "list", MemberRef: awssdkmodel.ShapeRef{Shape: tag}}},
+			"Labels":             {Shape: &awssdkmodel.Shape{Type: "map", ValueRef: awssdkmodel.ShapeRef{Shape: str}}},
+			"Optional":           {Shape: str},
+		},
+	}
+
+	want := map[string]interface{}{
+		"repositoryName":     "$REPOSITORY_NAME",
+		"imageTagMutability": "MUTABLE",
+		"maxImages":          int64(10),
+		"scanOnPush":         false,
+		"tags":               []interface{}{map[string]interface{}{"key": "$KEY"}},
+		"labels":             map[string]interface{}{"key": "$LABELS"},
+	}
+	placeholders := map[string]string{}
+	if got := sampleStructure(input, 0, placeholders); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	wantPlaceholders := map[string]string{
+		"REPOSITORY_NAME": "RepositoryName",
+		"KEY":             "Key",
+		"LABELS":          "Labels",
+	}
+	if !reflect.DeepEqual(placeholders, wantPlaceholders) {
+		t.Errorf("got placeholders %v, want %v", placeholders, wantPlaceholders)
+	}
+}
+
+func TestSampleStructureRecursive(t *testing.T) {
+	node := &awssdkmodel.Shape{Type: "structure", Required: []string{"Child"}}
+	node.MemberRefs = map[string]*awssdkmodel.ShapeRef{"Child": {Shape: node}}
+
+	depth := 0
+	for v := interface{}(sampleStructure(node, 0, map[string]string{})); v != nil; depth++ {
+		v = v.(map[string]interface{})["child"]
+	}
+	if depth > maxSampleDepth+1 {
+		t.Errorf("expected the sample to be at most %d levels deep, got %d", maxSampleDepth+1, depth)
+	}
+}
+
+func TestFindReadOperation(t *testing.T) {
+	str := &awssdkmodel.Shape{Type: "string"}
+	list := &awssdkmodel.Shape{Type: "list", MemberRef: awssdkmodel.ShapeRef{Shape: str}}
+	createInput := &awssdkmodel.Shape{
+		Type:     "structure",
+		Required: []string{"RepositoryName", "Mutability"},
+		MemberRefs: map[string]*awssdkmodel.ShapeRef{
+			"RepositoryName": {Shape: str},
+			"Mutability":     {Shape: str},
+		},
+	}
+	api := &awssdkmodel.API{Operations: map[string]*awssdkmodel.Operation{
+		"DescribeRepositories": {InputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{
+			Type:     "structure",
+			Required: []string{"RegistryId"},
+			MemberRefs: map[string]*awssdkmodel.ShapeRef{
+				"RegistryId":      {Shape: str},
+				"RepositoryNames": {Shape: list},
+			},
+		}}},
+		"ListRepositories": {InputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{Type: "structure"}}},
+		"ListLayers": {
+			InputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{Type: "structure"}},
+			OutputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{
+				Type:       "structure",
+				MemberRefs: map[string]*awssdkmodel.ShapeRef{"Layers": {Shape: list}},
+			}},
+		},
+		"ListImages": {
+			InputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{
+				Type:       "structure",
+				MemberRefs: map[string]*awssdkmodel.ShapeRef{"RepositoryName": {Shape: str}},
+			}},
+			OutputRef: awssdkmodel.ShapeRef{Shape: &awssdkmodel.Shape{
+				Type: "structure",
+				MemberRefs: map[string]*awssdkmodel.ShapeRef{
+					"ImageIds":  {Shape: list},
+					"NextToken": {Shape: str},
+				},
+			}},
+		},
+	}}
+
+	want := &readOperation{
+		Name: "DescribeRepositories",
+		Params: []readParam{
+			{Member: "RepositoryNames", Field: "repositoryName", List: true},
+			{Member: "RegistryId"},
+		},
+		Filtered: true,
+	}
+	if got := findReadOperation(api, "Repository", createInput); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// A List operation lists no resources once the resource is deleted
+	want = &readOperation{
+		Name:     "ListImages",
+		Params:   []readParam{{Member: "RepositoryName", Field: "repositoryName"}},
+		List:     true,
+		Result:   "ImageIds",
+		Filtered: true,
+	}
+	if got := findReadOperation(api, "Image", createInput); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// A List operation without parameters lists the other resources too
+	want = &readOperation{Name: "ListLayers", List: true, Result: "Layers"}
+	if got := findReadOperation(api, "Layer", createInput); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := findReadOperation(api, "Registry", createInput); got != nil {
+		t.Errorf("expected no read operation, got %+v", got)
+	}
+}
+
+func TestExampleSpec(t *testing.T) {
+	spec := map[string]interface{}{
+		"repositoryName": "$REPOSITORY_NAME",
+		"tags":           []interface{}{map[string]interface{}{"key": "$KEY", "value": "$VALUE"}},
+		"scanOnPush":     false,
+		"maxImages":      1,
+		"description":    "not a $PLACEHOLDER",
+	}
+	want := map[string]interface{}{
+		"repositoryName": "my-repository-name",
+		"tags":           []interface{}{map[string]interface{}{"key": "my-key", "value": "my-value"}},
+		"scanOnPush":     false,
+		"maxImages":      1,
+		"description":    "not a $PLACEHOLDER",
+	}
+	if got := exampleSpec(spec); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if spec["repositoryName"] != "$REPOSITORY_NAME" {
+		t.Error("the sample spec should not be modified")
+	}
+	if got := exampleSpec(nil); got != nil {
+		t.Errorf("expected no example spec, got %v", got)
+	}
+}
+
+func TestOLMConfigSamples(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(base, "olm/olmconfig.yaml.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := lintTestPack(t, fstest.MapFS{"olm/olmconfig.yaml.tpl": {Data: data}})
+	tplVars := testTemplateVars()
+	tplVars.crdModels = map[string]*crdModel{
+		"Repository": {spec: map[string]interface{}{"repositoryName": "$REPOSITORY_NAME"}},
+	}
+	targets, err := renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, _, err := renderFiles(context.Background(), newTemplate(""), targets, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var olmConfig struct {
+		DisplayName string `yaml:"displayName"`
+		Samples     []struct {
+			Kind string `yaml:"kind"`
+			Spec string `yaml:"spec"`
+		} `yaml:"samples"`
+		Links []struct {
+			Name string `yaml:"name"`
+		} `yaml:"links"`
+	}
+	if err = yaml.Unmarshal(rendered[0].contents, &olmConfig); err != nil {
+		t.Fatalf("cannot parse olmconfig.yaml: %v\n%s", err, rendered[0].contents)
+	}
+	// The service ID stands in for a missing abbreviation
+	if olmConfig.DisplayName != "AWS Controllers for Kubernetes - ECR" {
+		t.Errorf("got displayName %q", olmConfig.DisplayName)
+	}
+	if len(olmConfig.Links) != 1 || olmConfig.Links[0].Name != "ECR Developer Resources" {
+		t.Errorf("got links %+v", olmConfig.Links)
+	}
+
+	want := map[string]string{
+		"Repository":           `{"repositoryName":"my-repository-name"}`,
+		"PullThroughCacheRule": `{}`,
+	}
+	if len(olmConfig.Samples) != len(want) {
+		t.Fatalf("expected %d samples, got %+v", len(want), olmConfig.Samples)
+	}
+	for _, sample := range olmConfig.Samples {
+		if sample.Spec != want[sample.Kind] {
+			t.Errorf("got spec %q for %s, want %q", sample.Spec, sample.Kind, want[sample.Kind])
+		}
+		if !json.Valid([]byte(sample.Spec)) {
+			t.Errorf("the spec of %s is not JSON: %q", sample.Kind, sample.Spec)
+		}
+	}
+}
+
+func TestE2ETestDelete(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := fstest.MapFS{}
+	for _, path := range []string{"test/e2e/tests/test_{{.Resource.Snake}}.py.tpl", "_partials/header.py.tpl"} {
+		data, err := fs.ReadFile(base, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = &fstest.MapFile{Data: data}
+	}
+	pack := lintTestPack(t, files)
+	partials, err := pack.parsePartials()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param := readParam{Member: "RepositoryNames", Field: "repositoryName", List: true}
+	tests := []struct {
+		name string
+		read *readOperation
+		want string
+		// notWant must not be part of test_delete
+		notWant string
+	}{
+		{
+			"describe",
+			&readOperation{Name: "DescribeRepositories", Params: []readParam{param}},
+			"        with pytest.raises(ClientError):\n            describe_repository(ecr_client, cr)\n",
+			"assert not response",
+		},
+		{
+			"filtered list",
+			&readOperation{Name: "ListRepositories", Params: []readParam{param}, List: true, Result: "Repositories", Filtered: true},
+			"        response = describe_repository(ecr_client, cr)\n        assert not response[\"Repositories\"]\n",
+			"pytest.raises",
+		},
+		{
+			"unfiltered list",
+			&readOperation{Name: "ListRepositories", List: true, Result: "Repositories"},
+			"        # TODO: ListRepositories also lists the other Repositories of the\n",
+			"assert not response",
+		},
+	}
+	for _, tt := range tests {
+		tplVars := testTemplateVars()
+		tplVars.CRDNames = []string{"Repository"}
+		tplVars.crdModels = map[string]*crdModel{"Repository": {read: tt.read}}
+		targets, err := renderTargets(pack, tplVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, _, err := renderFiles(context.Background(), partials, targets, t.TempDir(), 1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		contents := string(rendered[0].contents)
+		testDelete := contents[strings.Index(contents, "def test_delete"):]
+		if !strings.Contains(testDelete, tt.want) {
+			t.Errorf("%s: expected test_delete to contain\n%s\ngot\n%s", tt.name, tt.want, testDelete)
+		}
+		if strings.Contains(testDelete, tt.notWant) {
+			t.Errorf("%s: expected test_delete not to contain %q:\n%s", tt.name, tt.notWant, testDelete)
+		}
+	}
+}
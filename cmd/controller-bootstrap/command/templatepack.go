@@ -0,0 +1,481 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	bootstrap "controller-bootstrap"
+)
+
+const (
+	// tombstoneSuffix marks an overlay file which deletes the file (or the
+	// directory) of the same name without the suffix from the lower layers.
+	tombstoneSuffix = ".tombstone"
+	// partialsDir is the directory of named templates shared by every
+	// template file. Partials are not rendered into the output.
+	partialsDir = "_partials"
+	// templateSuffix is trimmed from the template paths and partial names
+	templateSuffix = ".tpl"
+	// overlayRefSeparator separates the URL of a git overlay from the branch,
+	// tag or commit it is checked out at, e.g. https://host/pack.git@v1.0.0
+	overlayRefSeparator = "@"
+	// overlayFetchTTL is the duration for which the branches of a cached git
+	// overlay are used without fetching them again
+	overlayFetchTTL = time.Hour
+	// overlayFetchedFile records when a cached git overlay was last fetched
+	// in the modification time of the file inside its .git directory
+	overlayFetchedFile = "bootstrap-fetched"
+)
+
+// templatePack is the resolved set of template files, keyed by their slash
+// separated path relative to the template root.
+type templatePack struct {
+	files map[string]*templateFile
+}
+
+// templateLayer is a file system providing template files to the pack
+type templateLayer struct {
+	fs.FS
+	// dir is the local directory backing the layer, empty for the
+	// templates embedded in the binary
+	dir string
+}
+
+// templateFile is a single file of the template pack along with the layer
+// that provides it
+type templateFile struct {
+	layer *templateLayer
+	// mode is the file mode of the template file, symlinks are not followed
+	mode fs.FileMode
+}
+
+// newTemplatePack returns an empty templatePack
+func newTemplatePack() *templatePack {
+	return &templatePack{
+		files: map[string]*templateFile{},
+	}
+}
+
+// loadTemplatePack resolves the base template pack followed by every
+// overlay supplied with --template-overlay, in order. Files in an overlay
+// replace the files of the same path in the lower layers, new files are
+// added and tombstone files delete the matching files from the pack.
+func loadTemplatePack(ctx context.Context, cacheDir string) (*templatePack, error) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		return nil, err
+	}
+	pack := newTemplatePack()
+	if err = pack.addLayer(base); err != nil {
+		return nil, err
+	}
+	for _, overlay := range optTemplateOverlays {
+		overlayLayer, err := templateOverlayLayer(ctx, cacheDir, overlay)
+		if err != nil {
+			return nil, err
+		}
+		if err = pack.addLayer(overlayLayer); err != nil {
+			return nil, fmt.Errorf("unable to load template overlay %s: %v", overlay, err)
+		}
+	}
+	return pack, nil
+}
+
+// baseTemplateLayer returns the layer containing the base template files.
+// The templates embedded in the binary are used unless --template-dir is
+// supplied.
+func baseTemplateLayer() (*templateLayer, error) {
+	if optTemplateDir == "" {
+		embedded, err := fs.Sub(bootstrap.TemplateFS, "template")
+		if err != nil {
+			return nil, err
+		}
+		return &templateLayer{FS: embedded}, nil
+	}
+	info, err := os.Stat(optTemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template directory %s is not a directory", optTemplateDir)
+	}
+	return dirTemplateLayer(optTemplateDir), nil
+}
+
+// dirTemplateLayer returns the layer for the supplied local directory
+func dirTemplateLayer(dir string) *templateLayer {
+	return &templateLayer{
+		FS:  os.DirFS(dir),
+		dir: dir,
+	}
+}
+
+// templateOverlayLayer returns the layer for the supplied overlay, which
+// is either a local directory or the URL of a git repository, optionally
+// followed by "@" and the branch, tag or commit to use. Git repositories
+// are cloned once into the cache directory. Tags and commits are only
+// fetched again when they are missing from the clone, branches once they
+// were last fetched more than overlayFetchTTL ago.
+func templateOverlayLayer(
+	ctx context.Context,
+	cacheDir string,
+	overlay string,
+) (*templateLayer, error) {
+	if fi, err := os.Stat(overlay); err == nil {
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("expected template overlay %s to be a directory", overlay)
+		}
+		return dirTemplateLayer(overlay), nil
+	}
+	repoURL, ref := splitOverlayRef(overlay)
+	if !isGitURL(repoURL) {
+		return nil, fmt.Errorf("template overlay %s is neither a directory nor a git repository URL", overlay)
+	}
+
+	sum := sha256.Sum256([]byte(overlay))
+	overlayDir := filepath.Join(cacheDir, "src", "overlays", hex.EncodeToString(sum[:8]))
+	if err := checkoutOverlay(ctx, overlayDir, repoURL, ref); err != nil {
+		return nil, fmt.Errorf("cannot check out template overlay %s: %v", overlay, err)
+	}
+	return dirTemplateLayer(overlayDir), nil
+}
+
+// splitOverlayRef splits the supplied git overlay into the repository URL
+// and the ref following the last "@" of the repository path. The "@" of
+// the user info of a URL (e.g. git@github.com:org/pack.git) is not a ref
+// separator.
+func splitOverlayRef(overlay string) (string, string) {
+	i := strings.LastIndex(overlay, overlayRefSeparator)
+	if i < 0 {
+		return overlay, ""
+	}
+	pathStart := strings.Index(overlay, "://")
+	if pathStart >= 0 {
+		if slash := strings.Index(overlay[pathStart+3:], "/"); slash >= 0 {
+			pathStart += 3 + slash
+		} else {
+			pathStart = -1
+		}
+	} else {
+		pathStart = strings.Index(overlay, ":")
+	}
+	if pathStart < 0 || i < pathStart {
+		return overlay, ""
+	}
+	return overlay[:i], overlay[i+1:]
+}
+
+// checkoutOverlay clones the supplied git repository into dir, unless it
+// is already cached there, and checks out the supplied ref, or the default
+// branch of the repository when the ref is empty
+func checkoutOverlay(ctx context.Context, dir string, repoURL string, ref string) error {
+	repo, err := git.PlainOpen(dir)
+	if err == git.ErrRepositoryNotExists {
+		ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
+		defer cancel()
+		if err = CloneRepository(ct, dir, repoURL); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("cannot clone %s: %v", repoURL, err)
+		}
+		if repo, err = git.PlainOpen(dir); err != nil {
+			return err
+		}
+		if err = setRemoteHead(repo); err != nil {
+			return err
+		}
+		markOverlayFetched(dir)
+	} else if err != nil {
+		return err
+	}
+
+	hash, immutable, err := resolveOverlayRef(repo, ref)
+	if err != nil || (!immutable && overlayFetchExpired(dir)) {
+		ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
+		defer cancel()
+		fetchErr := repo.FetchContext(ct, &git.FetchOptions{Tags: git.AllTags, Force: true})
+		switch {
+		case fetchErr == nil || fetchErr == git.NoErrAlreadyUpToDate:
+			markOverlayFetched(dir)
+			hash, _, err = resolveOverlayRef(repo, ref)
+		case err == nil:
+			fmt.Fprintf(os.Stderr, "warning: cannot fetch %s, using the cached clone: %v\n", repoURL, fetchErr)
+		default:
+			return fmt.Errorf("cannot fetch %s: %v", repoURL, fetchErr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+}
+
+// setRemoteHead points refs/remotes/origin/HEAD at the remote branch of the
+// branch checked out by the clone, like `git clone` does, so that the
+// default branch can be resolved once HEAD is detached
+func setRemoteHead(repo *git.Repository) error {
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return nil
+	}
+	return repo.Storer.SetReference(plumbing.NewSymbolicReference(
+		plumbing.NewRemoteHEADReferenceName(git.DefaultRemoteName),
+		plumbing.NewRemoteReferenceName(git.DefaultRemoteName, head.Target().Short()),
+	))
+}
+
+// resolveOverlayRef returns the commit the supplied ref of a cached overlay
+// points to, and whether the ref is a tag or commit SHA which is not
+// expected to change. An empty ref is the default branch of the remote.
+func resolveOverlayRef(repo *git.Repository, ref string) (plumbing.Hash, bool, error) {
+	if commitSHARegexp.MatchString(ref) {
+		hash := plumbing.NewHash(ref)
+		if _, err := repo.CommitObject(hash); err != nil {
+			return plumbing.ZeroHash, true, fmt.Errorf("unknown commit %s", ref)
+		}
+		return hash, true, nil
+	}
+	var names []plumbing.ReferenceName
+	if ref == "" {
+		names = []plumbing.ReferenceName{plumbing.NewRemoteHEADReferenceName(git.DefaultRemoteName)}
+	} else {
+		names = []plumbing.ReferenceName{
+			plumbing.NewTagReferenceName(ref),
+			plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref),
+		}
+	}
+	for _, name := range names {
+		r, err := repo.Reference(name, true)
+		if err != nil {
+			continue
+		}
+		hash := r.Hash()
+		// annotated tags point to a tag object rather than to the commit
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+			hash = commit.Hash
+		}
+		return hash, name.IsTag(), nil
+	}
+	if ref == "" {
+		return plumbing.ZeroHash, false, fmt.Errorf("cannot resolve the default branch")
+	}
+	return plumbing.ZeroHash, false, fmt.Errorf("unknown branch, tag or commit %s", ref)
+}
+
+// overlayFetchExpired returns true if the cached overlay in dir was last
+// fetched more than overlayFetchTTL ago
+func overlayFetchExpired(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, git.GitDirName, overlayFetchedFile))
+	if err != nil {
+		return true
+	}
+	return timeNow().Sub(fi.ModTime()) >= overlayFetchTTL
+}
+
+// markOverlayFetched records that the cached overlay in dir was fetched
+func markOverlayFetched(dir string) {
+	path := filepath.Join(dir, git.GitDirName, overlayFetchedFile)
+	if err := ioutil.WriteFile(path, nil, 0644); err == nil {
+		now := timeNow()
+		os.Chtimes(path, now, now)
+	}
+}
+
+// isGitURL returns true if the supplied string looks like the URL of a
+// git repository, optionally followed by a ref
+func isGitURL(s string) bool {
+	s, _ = splitOverlayRef(s)
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(s, ".git")
+}
+
+// addLayer adds the files of the supplied layer on top of the files
+// already present in the pack. The tombstones of the layer only delete
+// files of the lower layers, never the files the layer provides itself.
+func (p *templatePack) addLayer(layer *templateLayer) error {
+	files := map[string]*templateFile{}
+	var tombstones []string
+	err := fs.WalkDir(layer, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, tombstoneSuffix) {
+			tombstones = append(tombstones, strings.TrimSuffix(path, tombstoneSuffix))
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = &templateFile{
+			layer: layer,
+			mode:  info.Mode(),
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range tombstones {
+		p.remove(name)
+	}
+	for path, f := range files {
+		p.files[path] = f
+	}
+	return nil
+}
+
+// remove deletes the file, or every file inside the directory, at the
+// supplied path from the pack
+func (p *templatePack) remove(name string) {
+	delete(p.files, name)
+	for file := range p.files {
+		if strings.HasPrefix(file, name+"/") {
+			delete(p.files, file)
+		}
+	}
+}
+
+// Paths returns the sorted paths of the files in the pack which are
+// rendered into the output, excluding the partials
+func (p *templatePack) Paths() []string {
+	paths := []string{}
+	for file := range p.files {
+		if !isPartial(file) {
+			paths = append(paths, file)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Partials returns the sorted paths of the partials in the pack
+func (p *templatePack) Partials() []string {
+	paths := []string{}
+	for file := range p.files {
+		if isPartial(file) {
+			paths = append(paths, file)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isPartial returns true if the supplied path is inside the partials directory
+func isPartial(name string) bool {
+	return strings.HasPrefix(name, partialsDir+"/")
+}
+
+// partialName returns the name a partial is referenced by from other
+// templates, which is its path inside the partials directory without the
+// template suffix (e.g. "_partials/header.py.tpl" -> "header.py")
+func partialName(name string) string {
+	name = strings.TrimPrefix(name, partialsDir+"/")
+	return strings.TrimSuffix(name, templateSuffix)
+}
+
+// parsePartials parses every partial in the pack into a single template
+// set which the template files are cloned from, so that any of them can
+// use `{{ template "header.py" . }}`. A single trailing newline is trimmed
+// from each partial so that it can be used in place of whole lines.
+//
+// Partials which fail to parse are left out of the set and returned as a
+// templateErrors.
+func (p *templatePack) parsePartials() (*template.Template, error) {
+	base := newTemplate(partialsDir)
+	var errs templateErrors
+	for _, partial := range p.Partials() {
+		contents, err := p.ReadFile(partial)
+		if err != nil {
+			errs = append(errs, newTemplateError(partial, phaseLoad, err))
+			continue
+		}
+		text := strings.TrimSuffix(string(contents), "\n")
+		if _, err = base.New(partialName(partial)).Parse(text); err != nil {
+			errs = append(errs, newTemplateError(partial, phaseParse, err))
+		}
+	}
+	return base, errs.errorOrNil()
+}
+
+// ReadFile returns the contents of the file at the supplied path
+func (p *templatePack) ReadFile(name string) ([]byte, error) {
+	name = path.Clean(name)
+	f, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s: %w", name, fs.ErrNotExist)
+	}
+	return fs.ReadFile(f.layer, name)
+}
+
+// Mode returns the file mode of the file at the supplied path. Symlinks
+// are not followed.
+func (p *templatePack) Mode(name string) (fs.FileMode, error) {
+	name = path.Clean(name)
+	f, ok := p.files[name]
+	if !ok {
+		return 0, fmt.Errorf("template %s: %w", name, fs.ErrNotExist)
+	}
+	return f.mode, nil
+}
+
+// Readlink returns the destination of the symlink at the supplied path.
+// Symlinks are only supported in template directories on the local disk.
+func (p *templatePack) Readlink(name string) (string, error) {
+	name = path.Clean(name)
+	f, ok := p.files[name]
+	if !ok {
+		return "", fmt.Errorf("template %s: %w", name, fs.ErrNotExist)
+	}
+	if f.layer.dir == "" {
+		return "", fmt.Errorf("template %s: symlinks are not supported in embedded templates", name)
+	}
+	return os.Readlink(filepath.Join(f.layer.dir, filepath.FromSlash(name)))
+}
@@ -0,0 +1,255 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestTemplatePackLayers(t *testing.T) {
+	pack := newTemplatePack()
+	layers := []fstest.MapFS{
+		{
+			"README.md.tpl":        {Data: []byte("base")},
+			"OWNERS.tpl":           {Data: []byte("base")},
+			"NOTICE.tpl":           {Data: []byte("base")},
+			"olm/olmconfig.yaml":   {Data: []byte("base")},
+			"olm/bundle/manifests": {Data: []byte("base")},
+		},
+		{
+			"README.md.tpl":        {Data: []byte("overlay")},
+			"OWNERS.tpl.tombstone": {},
+			"olm.tombstone":        {},
+			// a tombstone only deletes the files of the lower layers
+			"NOTICE.tpl":           {Data: []byte("overlay")},
+			"NOTICE.tpl.tombstone": {},
+			"CODEOWNERS.tpl":       {Data: []byte("overlay")},
+		},
+		{
+			"OWNERS.tpl": {Data: []byte("second overlay")},
+		},
+	}
+	for _, layer := range layers {
+		if err := pack.addLayer(&templateLayer{FS: layer}); err != nil {
+			t.Fatalf("unable to add layer: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		"CODEOWNERS.tpl": "overlay",
+		"NOTICE.tpl":     "overlay",
+		"OWNERS.tpl":     "second overlay",
+		"README.md.tpl":  "overlay",
+	}
+	paths := pack.Paths()
+	if len(paths) != len(want) {
+		t.Errorf("got paths %v, want %d files", paths, len(want))
+	}
+	for _, path := range paths {
+		contents, err := pack.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", path, err)
+		}
+		if string(contents) != want[path] {
+			t.Errorf("%s: got %q, want %q", path, contents, want[path])
+		}
+	}
+}
+
+func TestTemplateDir(t *testing.T) {
+	defer func(dir string, overlays []string) {
+		optTemplateDir, optTemplateOverlays = dir, overlays
+	}(optTemplateDir, optTemplateOverlays)
+	optTemplateOverlays = nil
+
+	// The embedded templates are used by default
+	optTemplateDir = ""
+	pack, err := loadTemplatePack(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pack.Paths()) < 2 {
+		t.Errorf("expected the embedded templates, got %v", pack.Paths())
+	}
+
+	// --template-dir replaces them
+	optTemplateDir = t.TempDir()
+	if err = ioutil.WriteFile(filepath.Join(optTemplateDir, "README.md.tpl"), []byte("# {{ .ServiceID }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pack, err = loadTemplatePack(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths := pack.Paths(); len(paths) != 1 || paths[0] != "README.md.tpl" {
+		t.Errorf("expected only the files of the template directory, got %v", paths)
+	}
+
+	tests := map[string]string{
+		filepath.Join(optTemplateDir, "missing"):       "unable to read template directory",
+		filepath.Join(optTemplateDir, "README.md.tpl"): "is not a directory",
+	}
+	for dir, wantErr := range tests {
+		optTemplateDir = dir
+		if _, err = loadTemplatePack(context.Background(), t.TempDir()); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: got error %v, want %q", dir, err, wantErr)
+		}
+	}
+}
+
+func TestParsePartials(t *testing.T) {
+	pack := lintTestPack(t, fstest.MapFS{
+		"_partials/header.py.tpl":    {Data: []byte("# {{ .ServiceID }}\n")},
+		"_partials/sections/faq.tpl": {Data: []byte("FAQ\n\n")},
+		"_partials/broken.tpl":       {Data: []byte("{{ if }}")},
+		"README.md.tpl":              {Data: []byte("{{ template \"header.py\" . }}\n")},
+	})
+	if paths := pack.Paths(); len(paths) != 1 || paths[0] != "README.md.tpl" {
+		t.Errorf("partials should not be rendered, got paths %v", paths)
+	}
+
+	partials, err := pack.parsePartials()
+	var errs templateErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected a single template error, got %v", err)
+	}
+	if errs[0].File != "_partials/broken.tpl" || errs[0].Phase != phaseParse {
+		t.Errorf("got error for %s in phase %s", errs[0].File, errs[0].Phase)
+	}
+
+	// a single trailing newline is trimmed from each partial
+	tests := map[string]string{
+		"header.py":    "# ECR",
+		"sections/faq": "FAQ\n",
+	}
+	for name, want := range tests {
+		var buf bytes.Buffer
+		if err = partials.ExecuteTemplate(&buf, name, map[string]string{"ServiceID": "ECR"}); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", name, buf.String(), want)
+		}
+	}
+	if partials.Lookup("broken") != nil {
+		t.Error("a partial which fails to parse should be left out")
+	}
+}
+
+func TestSplitOverlayRef(t *testing.T) {
+	tests := []struct {
+		overlay string
+		url     string
+		ref     string
+	}{
+		{"https://github.com/org/pack", "https://github.com/org/pack", ""},
+		{"https://github.com/org/pack.git@v1.0.0", "https://github.com/org/pack.git", "v1.0.0"},
+		{"https://user@github.com/org/pack@main", "https://user@github.com/org/pack", "main"},
+		{"https://user@github.com/org/pack", "https://user@github.com/org/pack", ""},
+		{"git@github.com:org/pack.git", "git@github.com:org/pack.git", ""},
+		{"git@github.com:org/pack.git@release-1", "git@github.com:org/pack.git", "release-1"},
+	}
+	for _, tt := range tests {
+		url, ref := splitOverlayRef(tt.overlay)
+		if url != tt.url || ref != tt.ref {
+			t.Errorf("splitOverlayRef(%q) = %q, %q, want %q, %q", tt.overlay, url, ref, tt.url, tt.ref)
+		}
+	}
+}
+
+// commitOverlayFile commits a README.md.tpl with the supplied contents to
+// the repository in dir and returns the commit
+func commitOverlayFile(t *testing.T, repo *git.Repository, dir string, contents string) plumbing.Hash {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md.tpl"), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Add("README.md.tpl"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := wt.Commit(contents, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+func TestTemplateOverlayGitRef(t *testing.T) {
+	src := t.TempDir()
+	repo, err := git.PlainInit(src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := commitOverlayFile(t, repo, src, "v1")
+	if _, err = repo.CreateTag("v1.0.0", v1, nil); err != nil {
+		t.Fatal(err)
+	}
+	v2 := commitOverlayFile(t, repo, src, "v2")
+
+	defer func() { timeNow = time.Now }()
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	cacheDir := t.TempDir()
+	url := "file://" + src
+	assertOverlay := func(overlay string, want string) {
+		t.Helper()
+		layer, err := templateOverlayLayer(context.Background(), cacheDir, overlay)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", overlay, err)
+		}
+		contents, err := fs.ReadFile(layer, "README.md.tpl")
+		if err != nil {
+			t.Fatalf("%s: %v", overlay, err)
+		}
+		if string(contents) != want {
+			t.Errorf("%s: got %q, want %q", overlay, contents, want)
+		}
+	}
+
+	assertOverlay(url+"@v1.0.0", "v1")
+	assertOverlay(url+"@"+v2.String(), "v2")
+	assertOverlay(url, "v2")
+
+	// The default branch is only fetched again once the fetch expired
+	commitOverlayFile(t, repo, src, "v3")
+	assertOverlay(url, "v2")
+	now = now.Add(overlayFetchTTL)
+	assertOverlay(url, "v3")
+	assertOverlay(url+"@master", "v3")
+	assertOverlay(url+"@v1.0.0", "v1")
+
+	if _, err = templateOverlayLayer(context.Background(), cacheDir, url+"@v9.9.9"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
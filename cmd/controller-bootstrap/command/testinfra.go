@@ -0,0 +1,51 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// testInfraCheckout returns the path of the local aws-controllers-k8s/test-infra
+// checkout, which is either supplied with --test-infra-path or a test-infra
+// directory next to the output directory. An empty path is returned when
+// there is no checkout.
+func testInfraCheckout() string {
+	if optTestInfraPath != "" {
+		return optTestInfraPath
+	}
+	path := filepath.Join(filepath.Dir(filepath.Clean(optOutputPath)), "test-infra")
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		return ""
+	}
+	return path
+}
+
+// checkTestInfraCommit returns an error if the supplied commit does not
+// exist in the test-infra checkout
+func checkTestInfraCommit(path, sha string) error {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return fmt.Errorf("cannot open test-infra checkout %s: %v", path, err)
+	}
+	if _, err = repo.CommitObject(plumbing.NewHash(sha)); err != nil {
+		return fmt.Errorf("commit %s not found in test-infra checkout %s, update it or supply another --test-infra-commit-sha", sha, path)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+func TestCheckTestInfraCommit(t *testing.T) {
+	path := testTaggedRepo(t)
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = checkTestInfraCommit(path, head.Hash().String()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err = checkTestInfraCommit(path, testCommitSHA); err == nil {
+		t.Errorf("expected an error for the unknown commit %s", testCommitSHA)
+	}
+	if err = checkTestInfraCommit(t.TempDir(), testCommitSHA); err == nil {
+		t.Error("expected an error for a directory which is not a git repository")
+	}
+}
+
+func TestTestInfraCommitTemplates(t *testing.T) {
+	base, err := baseTemplateLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The files referencing test-infra, which must pin the same commit
+	want := map[string][]string{
+		"test/e2e/requirements.txt": {"test-infra.git@" + testCommitSHA + "\n"},
+		".github/workflows/e2e-test.yml": {
+			"TEST_INFRA_COMMIT_SHA: " + testCommitSHA + "\n",
+			"ref: ${{ env.TEST_INFRA_COMMIT_SHA }}\n",
+		},
+	}
+	files := fstest.MapFS{}
+	for path := range want {
+		data, err := fs.ReadFile(base, path+".tpl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path+".tpl"] = &fstest.MapFile{Data: data}
+	}
+
+	pack := lintTestPack(t, files)
+	partials, err := pack.parsePartials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tplVars := testTemplateVars()
+	tplVars.TestInfraCommitSHA = testCommitSHA
+	targets, err := renderTargets(pack, tplVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, _, err := renderFiles(context.Background(), partials, targets, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rendered) != len(want) {
+		t.Fatalf("expected %d rendered files, got %d", len(want), len(rendered))
+	}
+	for _, f := range rendered {
+		for _, line := range want[f.file] {
+			if !strings.Contains(string(f.contents), line) {
+				t.Errorf("expected %s to contain %q:\n%s", f.file, line, f.contents)
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const (
+	// versionLatest is resolved to the latest release of a repository
+	versionLatest = "latest"
+	// runtimeRepoURL is the default repository of aws-controllers-k8s/runtime
+	runtimeRepoURL = "https://github.com/aws-controllers-k8s/runtime"
+	// versionCacheFile caches the resolved latest versions in the cache directory
+	versionCacheFile = "latest-versions.json"
+	// versionCacheTTL is the duration for which resolved latest versions are reused
+	versionCacheTTL = time.Hour
+)
+
+// cachedVersion is a latest version resolved for a repository
+type cachedVersion struct {
+	Version    string    `json:"version"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+// versionCache maps repository URLs to their latest resolved versions
+type versionCache map[string]cachedVersion
+
+// resolveLatestVersions replaces the versions set to "latest" with the latest
+// releases. The aws-sdk-go version is resolved from the tags of the local
+// clone and the runtime version from the tags of the runtime repository.
+func resolveLatestVersions(ctx context.Context, cacheDir string) error {
+	if optAWSSDKGoVersion != versionLatest && optRuntimeVersion != versionLatest {
+		return nil
+	}
+	cachePath := filepath.Join(cacheDir, versionCacheFile)
+	cache := loadVersionCache(cachePath)
+
+	var err error
+	if optAWSSDKGoVersion == versionLatest {
+		optAWSSDKGoVersion, err = cache.resolve(sdkRepoURL, func() ([]string, error) {
+			return localRepoTags(ctx, sdkDir)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot resolve the latest aws-sdk-go version: %v", err)
+		}
+	}
+	if optRuntimeVersion == versionLatest {
+		optRuntimeVersion, err = cache.resolve(optRuntimeRepoURL, func() ([]string, error) {
+			return remoteRepoTags(ctx, optRuntimeRepoURL)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot resolve the latest aws-controllers-k8s/runtime version: %v", err)
+		}
+	}
+	return cache.save(cachePath)
+}
+
+// loadVersionCache reads the cache of latest versions. A missing or invalid
+// cache is treated as empty.
+func loadVersionCache(path string) versionCache {
+	cache := versionCache{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err = json.Unmarshal(data, &cache); err != nil {
+		return versionCache{}
+	}
+	return cache
+}
+
+// save writes the cache of latest versions
+func (c versionCache) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// resolve returns the latest version of a repository, either from the cache
+// or from the tags returned by listTags when the cached version expired
+func (c versionCache) resolve(repoURL string, listTags func() ([]string, error)) (string, error) {
+	if cached, ok := c[repoURL]; ok && timeNow().Sub(cached.ResolvedAt) < versionCacheTTL {
+		return cached.Version, nil
+	}
+	tags, err := listTags()
+	if err != nil {
+		return "", err
+	}
+	version := latestSemver(tags)
+	if version == "" {
+		return "", fmt.Errorf("no release tags found in %s", repoURL)
+	}
+	c[repoURL] = cachedVersion{Version: version, ResolvedAt: timeNow()}
+	return version, nil
+}
+
+// localRepoTags returns the tags of a local git clone, after fetching the
+// tags of its origin. The local tags are used when the fetch fails.
+func localRepoTags(ctx context.Context, path string) ([]string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %v", path, err)
+	}
+	ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
+	defer cancel()
+	err = repo.FetchContext(ct, &git.FetchOptions{Tags: git.AllTags})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		fmt.Fprintf(os.Stderr, "warning: cannot fetch the tags of %s, using the local tags: %v\n", path, err)
+	}
+
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tags = append(tags, ref.Name().Short())
+		return nil
+	})
+	return tags, err
+}
+
+// remoteRepoTags returns the tags of a git repository without cloning it.
+// Calling this function is equivalent to executing `git ls-remote --tags $repositoryURL`.
+// Listing is given up when the context is done or defaultGitCloneTimeout
+// expires, as go-git cannot cancel it.
+func remoteRepoTags(ctx context.Context, repositoryURL string) ([]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{repositoryURL},
+	})
+	ct, cancel := context.WithTimeout(ctx, defaultGitCloneTimeout)
+	defer cancel()
+	type listResult struct {
+		refs []*plumbing.Reference
+		err  error
+	}
+	// buffered, so that the goroutine does not leak once it is given up
+	done := make(chan listResult, 1)
+	go func() {
+		refs, err := remote.List(&git.ListOptions{})
+		done <- listResult{refs, err}
+	}()
+
+	var res listResult
+	select {
+	case <-ct.Done():
+		return nil, fmt.Errorf("cannot list the tags of %s: %v", repositoryURL, ct.Err())
+	case res = <-done:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	var tags []string
+	for _, ref := range res.refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	return tags, nil
+}
+
+// latestSemver returns the highest release version of the supplied tags.
+// Pre-release versions are ignored.
+func latestSemver(tags []string) string {
+	var latest string
+	var latestParts [3]int
+	for _, tag := range tags {
+		if !semverRegexp.MatchString(tag) || strings.ContainsAny(tag, "-+") {
+			continue
+		}
+		var parts [3]int
+		for i, s := range strings.SplitN(strings.TrimPrefix(tag, "v"), ".", 3) {
+			parts[i], _ = strconv.Atoi(s)
+		}
+		if latest == "" || compareVersionParts(parts, latestParts) > 0 {
+			latest, latestParts = tag, parts
+		}
+	}
+	return latest
+}
+
+// compareVersionParts compares the major, minor and patch numbers of two versions
+func compareVersionParts(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestLatestSemver(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"main", "release"}, ""},
+		{[]string{"v0.1.0", "v0.2.0", "v0.10.0", "v0.9.1"}, "v0.10.0"},
+		{[]string{"v1.44.25", "v1.44.3", "v2.0.0-rc.1", "1.50.0"}, "v1.44.25"},
+		{[]string{"v0.19.0", "v0.19.0^{}"}, "v0.19.0"},
+	}
+	for _, tt := range tests {
+		if got := latestSemver(tt.tags); got != tt.want {
+			t.Errorf("latestSemver(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+// testTaggedRepo creates a git repository with a commit carrying the supplied
+// tags and returns the path of a bare clone of it
+func testTaggedRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("runtime\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range tags {
+		if _, err = repo.CreateTag(tag, commit, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bare := filepath.Join(t.TempDir(), "runtime.git")
+	if _, err = git.PlainClone(bare, true, &git.CloneOptions{URL: dir, Tags: git.AllTags}); err != nil {
+		t.Fatal(err)
+	}
+	return bare
+}
+
+func TestResolveLatestRuntimeVersion(t *testing.T) {
+	bare := testTaggedRepo(t, "v0.1.0", "v0.18.2", "v0.19.0", "v0.20.0-rc.1")
+	cacheDir := t.TempDir()
+
+	defer func(runtime, sdk, repo string) {
+		optRuntimeVersion, optAWSSDKGoVersion, optRuntimeRepoURL = runtime, sdk, repo
+		timeNow = time.Now
+	}(optRuntimeVersion, optAWSSDKGoVersion, optRuntimeRepoURL)
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	optRuntimeRepoURL = bare
+	optAWSSDKGoVersion = "v1.44.25"
+	optRuntimeVersion = versionLatest
+	if err := resolveLatestVersions(context.Background(), cacheDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if optRuntimeVersion != "v0.19.0" {
+		t.Errorf("got runtime version %q, want v0.19.0", optRuntimeVersion)
+	}
+	if optAWSSDKGoVersion != "v1.44.25" {
+		t.Errorf("an explicit aws-sdk-go version should not be resolved, got %q", optAWSSDKGoVersion)
+	}
+
+	// A new release is only picked up once the cached version expired
+	bareRepo, err := git.PlainOpen(bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := bareRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = bareRepo.CreateTag("v0.19.1", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	optRuntimeVersion = versionLatest
+	if err = resolveLatestVersions(context.Background(), cacheDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if optRuntimeVersion != "v0.19.0" {
+		t.Errorf("expected the cached version v0.19.0, got %q", optRuntimeVersion)
+	}
+
+	now = now.Add(versionCacheTTL)
+	optRuntimeVersion = versionLatest
+	if err = resolveLatestVersions(context.Background(), cacheDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if optRuntimeVersion != "v0.19.1" {
+		t.Errorf("got runtime version %q after the cache expired, want v0.19.1", optRuntimeVersion)
+	}
+}
+
+func TestResolveLatestSDKVersion(t *testing.T) {
+	bare := testTaggedRepo(t, "v1.44.24", "v1.44.25")
+	clone := filepath.Join(t.TempDir(), "aws-sdk-go")
+	if _, err := git.PlainClone(clone, false, &git.CloneOptions{URL: bare, Tags: git.AllTags}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(runtime, sdk, dir string) {
+		optRuntimeVersion, optAWSSDKGoVersion, sdkDir = runtime, sdk, dir
+	}(optRuntimeVersion, optAWSSDKGoVersion, sdkDir)
+	sdkDir = clone
+	optRuntimeVersion = "v0.19.0"
+	optAWSSDKGoVersion = versionLatest
+	if err := resolveLatestVersions(context.Background(), t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if optAWSSDKGoVersion != "v1.44.25" {
+		t.Errorf("got aws-sdk-go version %q, want v1.44.25", optAWSSDKGoVersion)
+	}
+}
+
+func TestRemoteRepoTagsCancelled(t *testing.T) {
+	// a git server which accepts connections but never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := remoteRepoTags(ctx, "git://"+l.Addr().String()+"/runtime.git")
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Errorf("expected a deadline exceeded error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("listing the tags was not interrupted by the context")
+	}
+
+	tags, err := remoteRepoTags(context.Background(), testTaggedRepo(t, "v0.19.0"))
+	if err != nil || len(tags) != 1 || tags[0] != "v0.19.0" {
+		t.Errorf("got tags %v and error %v, want v0.19.0", tags, err)
+	}
+}
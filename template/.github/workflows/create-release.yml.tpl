@@ -1,3 +1,7 @@
+---
+when:
+  - not .ExistingController
+---
 name: Create Release
 
 on:
@@ -0,0 +1,47 @@
+---
+mode: create-only
+---
+name: E2E Tests
+
+on:
+  pull_request:
+    branches:
+      - main
+  workflow_dispatch: {}
+
+permissions:
+  contents: read
+  id-token: write # For assuming the AWS role of the tests
+
+env:
+  SERVICE: {{ .ServicePackageName }}
+  # The aws-controllers-k8s/test-infra commit the e2e tests run against,
+  # keep it in sync with acktest in test/e2e/requirements.txt
+  TEST_INFRA_COMMIT_SHA: {{ .TestInfraCommitSHA }}
+
+jobs:
+  kind-test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          path: {{ .ServicePackageName }}-controller
+      - uses: actions/checkout@v3
+        with:
+          repository: aws-controllers-k8s/code-generator
+          path: code-generator
+      - uses: actions/checkout@v3
+        with:
+          repository: aws-controllers-k8s/test-infra
+          ref: ${{ "{{" }} env.TEST_INFRA_COMMIT_SHA }}
+          path: test-infra
+      - uses: actions/setup-go@v3
+        with:
+          go-version-file: {{ .ServicePackageName }}-controller/go.mod
+      - uses: aws-actions/configure-aws-credentials@v2
+        with:
+          role-to-assume: ${{ "{{" }} secrets.AWS_ROLE_ARN }}
+          aws-region: us-west-2
+      - name: Run the e2e tests in a KinD cluster
+        working-directory: {{ .ServicePackageName }}-controller
+        run: make kind-test
@@ -1 +1,64 @@
+---
+mode: create-only
+---
+SHELL := /bin/bash # Use bash syntax
 
+# Set up variables
+GO111MODULE=on
+
+SERVICE={{ .ServicePackageName }}
+CONTROLLER_MODULE={{ .ModulePath }}
+ACK_RUNTIME_VERSION ?= {{ .RuntimeVersion }}
+
+ROOT_DIR=$(shell dirname $(realpath $(firstword $(MAKEFILE_LIST))))
+CODE_GEN_DIR ?= ${ROOT_DIR}/../code-generator
+TEST_INFRA_DIR ?= ${ROOT_DIR}/../test-infra
+
+# Build ldflags
+VERSION ?= "v0.0.0"
+GITCOMMIT=$(shell git rev-parse HEAD)
+BUILDDATE=$(shell date -u +'%Y-%m-%dT%H:%M:%SZ')
+GO_LDFLAGS=-ldflags "-X main.version=$(VERSION) \
+			-X main.buildHash=$(GITCOMMIT) \
+			-X main.buildDate=$(BUILDDATE)"
+
+.PHONY: all build build-controller test local-test local-run-controller \
+	kind-test build-bundle helm-lint release help
+
+all: test
+
+build:				## Build the controller binary
+	go build ${GO_LDFLAGS} -o bin/controller ${CONTROLLER_MODULE}/cmd/controller
+
+build-controller:		## Generate the controller code with the code-generator
+	@cd ${CODE_GEN_DIR} && ACK_RUNTIME_VERSION=${ACK_RUNTIME_VERSION} \
+		make build-controller SERVICE=${SERVICE}
+
+test:				## Run code tests
+	go test -v ${CONTROLLER_MODULE}/...
+
+local-test:			## Run code tests using go.local.mod file
+	go test -modfile=go.local.mod -v ${CONTROLLER_MODULE}/...
+
+local-run-controller:		## Run the controller locally
+	@go run ./cmd/controller/main.go \
+		--aws-region=us-west-2 \
+		--enable-development-logging \
+		--log-level=debug
+
+kind-test:			## Run the e2e tests in a local KinD cluster
+	@cd ${TEST_INFRA_DIR} && make kind-test SERVICE=${SERVICE}
+
+build-bundle:			## Build the OLM bundle for VERSION
+	@cd ${CODE_GEN_DIR} && ./scripts/olm-create-bundle.sh ${SERVICE} ${VERSION}
+
+helm-lint:			## Lint the Helm chart
+	helm lint ${ROOT_DIR}/helm
+
+release:			## Generate the release artifacts for VERSION
+	@cd ${CODE_GEN_DIR} && ACK_RUNTIME_VERSION=${ACK_RUNTIME_VERSION} \
+		make build-controller-release SERVICE=${SERVICE} RELEASE_VERSION=${VERSION}
+
+help:				## Show this help.
+	@grep -F -h "##" $(MAKEFILE_LIST) | grep -F -v grep | sed -e 's/\\$$//' \
+		| awk -F'[:#]' '{print $$1 = sprintf("%-30s", $$1), $$4}'
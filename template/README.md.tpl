@@ -2,25 +2,25 @@
 
 This repository contains source code for the AWS Controllers for Kubernetes
 (ACK) service controller for {{ .ServiceAbbreviation }}.
+{{- with .ServiceDocumentation }}
+
+{{ wrap 79 . }}
+{{- end }}
 
 Please [log issues][ack-issues] and feedback on the main AWS Controllers for
 Kubernetes Github project.
 
 [ack-issues]: https://github.com/aws/aws-controllers-k8s/issues
 
-## Contributing
-
-We welcome community contributions and pull requests.
-
-See our [contribution guide](/CONTRIBUTING.md) for more information on how to
-report issues, set up a development environment, and submit code.
-
-We adhere to the [Amazon Open Source Code of Conduct][coc].
+## Building
 
-You can also learn more about our [Governance](/GOVERNANCE.md) structure.
+The controller is the Go module `{{ .ModulePath }}` and requires Go
+{{ .GoVersion }} or later. Build it with:
 
-[coc]: https://aws.github.io/code-of-conduct
+```
+go build ./cmd/controller
+```
 
-## License
+{{ template "readme.contributing.md" . }}
 
-This project is [licensed](/LICENSE) under the Apache-2.0 License.
+{{ template "readme.license.md" . }}
@@ -0,0 +1,3 @@
+## License
+
+This project is [licensed](/LICENSE) under the Apache-2.0 License.
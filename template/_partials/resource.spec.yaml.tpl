@@ -0,0 +1,2 @@
+spec:{{ with .Resource.Spec }}
+{{ toYaml . | indent 2 }}{{ else }} {}{{ end }}
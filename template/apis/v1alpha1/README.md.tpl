@@ -1 +0,0 @@
-This is README.md for apis/v1alpha1 directory
\ No newline at end of file
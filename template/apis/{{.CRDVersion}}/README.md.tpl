@@ -0,0 +1 @@
+This is README.md for apis/{{ .CRDVersion }} directory
\ No newline at end of file
@@ -0,0 +1,8 @@
+---
+mode: create-only
+---
+apiVersion: {{ .ServicePackageName }}.services.k8s.aws/{{ .CRDVersion }}
+kind: {{ .Resource.Name }}
+metadata:
+  name: {{ .Resource.Kebab }}-sample
+{{ template "resource.spec.yaml" . }}
@@ -1,10 +1,9 @@
 ignore:
   resource_names:
-{{- range $crdName := .CRDNames }}
-      - {{ $crdName }}
+{{- range .CRDNames }}
+      - {{ . }}
 {{- end }}
-{{ $serviceModelName := .ServiceModelName }}
-{{- if not (eq $serviceModelName "") -}}
-    {{ $serviceModelName = .ServiceModelName }}
-model_name: {{ $serviceModelName }}
+{{- with .ServiceModelName }}
+
+model_name: {{ . }}
 {{- end }}
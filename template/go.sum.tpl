@@ -0,0 +1,6 @@
+---
+when:
+  - .GoSum
+---
+{{ range .GoSum }}{{ . }}
+{{ end -}}
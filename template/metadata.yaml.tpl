@@ -1,8 +1,8 @@
 service:
-  full_name: "{{ .ServiceFullName }}"
-  short_name: "{{ .ServiceID }}"
-  link:
-  documentation:
+  full_name: {{ quote .ServiceFullName }}
+  short_name: {{ quote .ServiceID }}
+  link: {{ quote .ServiceLink }}
+  documentation: {{ quote .ServiceDocumentationURL }}
 api_versions:
-  - api_version:
-    status:
+  - api_version: {{ .CRDVersion }}
+    status: {{ .APIStatus }}
@@ -0,0 +1,43 @@
+---
+mode: create-only
+---
+# This file configures the OLM bundle of the controller built by
+# `make build-bundle` in the code-generator repository.
+annotations:
+  capabilityLevel: Basic Install
+  shortDescription: AWS {{ .ServicePackageName }} controller is a service controller for managing {{ .ServicePackageName }} resources
+    in Kubernetes
+displayName: AWS Controllers for Kubernetes - {{ default .ServiceID .ServiceAbbreviation }}
+description: |-
+  Manage {{ .ServiceFullName }} ({{ .ServiceID }}) resources in AWS from within your Kubernetes cluster.
+{{- with .ServiceDocumentation }}
+
+  **About {{ default $.ServiceID $.ServiceAbbreviation }}**
+
+{{ wrap 78 . | indent 2 }}
+{{- end }}
+
+  **About the AWS Controllers for Kubernetes**
+
+  This controller is a component of the [AWS Controller for Kubernetes](https://github.com/aws/aws-controllers-k8s)
+  project. This project is currently in **developer preview**.
+
+  **Pre-Installation Steps**
+
+  Please follow the following link: [Red Hat OpenShift](https://aws-controllers-k8s.github.io/community/docs/user-docs/openshift/)
+keywords:
+- {{ .ServicePackageName }}
+- aws
+- amazon
+- ack
+samples:{{ if not .CRDNames }} []{{ end }}
+{{- range .Resources }}
+- kind: {{ .Name }}
+  spec: {{ with .ExampleSpec }}{{ toJson . | quote }}{{ else }}'{}'{{ end }}
+{{- end }}
+maintainers:
+- name: "{{ .ServicePackageName }} maintainer team"
+  email: "ack-maintainers@amazon.com"
+links:
+- name: {{ default .ServiceID .ServiceAbbreviation }} Developer Resources
+  url: {{ .ServiceLink }}
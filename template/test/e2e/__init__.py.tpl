@@ -1,15 +1,7 @@
-# Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
-#
-# Licensed under the Apache License, Version 2.0 (the "License"). You may
-# not use this file except in compliance with the License. A copy of the
-# License is located at
-#
-#	 http://aws.amazon.com/apache2.0/
-#
-# or in the "license" file accompanying this file. This file is distributed
-# on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
-# express or implied. See the License for the specific language governing
-# permissions and limitations under the License.
+---
+mode: create-only
+---
+{{ template "header.py" . }}
 
 import pytest
 from typing import Dict, Any
@@ -19,10 +11,17 @@ from acktest.resources import load_resource_file
 
 SERVICE_NAME = "{{ .ServicePackageName }}"
 CRD_GROUP = "{{ .ServicePackageName }}.services.k8s.aws"
-CRD_VERSION = "v1alpha1"
+CRD_VERSION = "{{ .CRDVersion }}"
 
 # PyTest marker for the current service
 service_marker = pytest.mark.service(arg=SERVICE_NAME)
 
 bootstrap_directory = Path(__file__).parent
 resource_directory = Path(__file__).parent / "resources"
+
+
+def load_{{ .ServicePackageName }}_resource(resource_name: str, additional_replacements: Dict[str, Any] = {}):
+    """ Overrides the default `load_resource_file` to access the specific resources
+    directory for the current service.
+    """
+    return load_resource_file(resource_directory, resource_name, additional_replacements=additional_replacements)
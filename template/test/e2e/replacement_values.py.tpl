@@ -1,17 +1,14 @@
-# Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
-#
-# Licensed under the Apache License, Version 2.0 (the "License"). You may
-# not use this file except in compliance with the License. A copy of the
-# License is located at
-#
-#	 http://aws.amazon.com/apache2.0/
-#
-# or in the "license" file accompanying this file. This file is distributed
-# on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
-# express or implied. See the License for the specific language governing
-# permissions and limitations under the License.
+---
+mode: create-only
+---
+{{ template "header.py" . }}
+
 """Stores the values used by each of the integration tests for replacing the
 {{ .ServiceID }}-specific test variables.
+
+These override the values controller-bootstrap generates into
+generated_replacement_values.py from the placeholders of the resources in
+test/e2e/resources.
 """
 
 REPLACEMENT_VALUES = {
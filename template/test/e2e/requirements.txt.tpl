@@ -0,0 +1 @@
+acktest @ git+https://github.com/aws-controllers-k8s/test-infra.git@{{ .TestInfraCommitSHA }}
@@ -0,0 +1,8 @@
+---
+mode: create-only
+---
+apiVersion: {{ .ServicePackageName }}.services.k8s.aws/{{ .CRDVersion }}
+kind: {{ .Resource.Name }}
+metadata:
+  name: ${{ upper .Resource.Snake }}_NAME
+{{ template "resource.spec.yaml" . }}
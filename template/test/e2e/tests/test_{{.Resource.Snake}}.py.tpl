@@ -0,0 +1,142 @@
+---
+mode: create-only
+---
+{{ template "header.py" . }}
+
+"""Integration tests for the {{ .ServiceID }} {{ .Resource.Name }} resource.
+"""
+
+import time
+
+import boto3
+import pytest
+from botocore.exceptions import ClientError
+
+from acktest.k8s import resource as k8s
+from acktest.resources import random_suffix_name
+from e2e import service_marker, CRD_GROUP, CRD_VERSION, SERVICE_NAME, load_{{ .ServicePackageName }}_resource
+from e2e.generated_replacement_values import generated_replacement_values
+from e2e.replacement_values import REPLACEMENT_VALUES
+
+RESOURCE_PLURAL = "{{ lower .Resource.Plural }}"
+
+CREATE_WAIT_AFTER_SECONDS = 10
+MODIFY_WAIT_AFTER_SECONDS = 10
+DELETE_WAIT_AFTER_SECONDS = 10
+SYNCED_WAIT_PERIODS = 10
+
+
+@pytest.fixture(scope="module")
+def {{ .ServicePackageName }}_client():
+    return boto3.client(SERVICE_NAME)
+
+
+def wait_for_synced(ref):
+    assert k8s.wait_on_condition(ref, "ACK.ResourceSynced", "True", wait_periods=SYNCED_WAIT_PERIODS)
+
+
+def describe_{{ .Resource.Snake }}(client, cr):
+{{- with .Resource.Read }}
+    return client.{{ snake .Name }}({{ if not .Params }}){{ else }}
+{{- range .Params }}
+{{- if not .Field }}
+        # TODO: {{ .Member }}=...,
+{{- else if .List }}
+        {{ .Member }}=[cr["spec"]["{{ .Field }}"]],
+{{- else }}
+        {{ .Member }}=cr["spec"]["{{ .Field }}"],
+{{- end }}
+{{- end }}
+    ){{ end }}
+{{- else }}
+    # TODO: describe the {{ .Resource.Name }} resource, {{ .ServiceID }} has no
+    # Describe{{ .Resource.Name }} or Get{{ .Resource.Name }} API
+    raise NotImplementedError
+{{- end }}
+
+
+@pytest.fixture
+def {{ .Resource.Snake }}():
+    resource_name = random_suffix_name("{{ .Resource.Kebab }}", 32)
+    replacements = generated_replacement_values()
+    replacements.update(REPLACEMENT_VALUES)
+    replacements["{{ upper .Resource.Snake }}_NAME"] = resource_name
+
+    resource_data = load_{{ .ServicePackageName }}_resource(
+        "{{ .Resource.Snake }}",
+        additional_replacements=replacements,
+    )
+
+    ref = k8s.CustomResourceReference(
+        CRD_GROUP, CRD_VERSION, RESOURCE_PLURAL,
+        resource_name, namespace="default",
+    )
+    k8s.create_custom_resource(ref, resource_data)
+    cr = k8s.wait_resource_consumed_by_controller(ref)
+    assert cr is not None
+    assert k8s.get_resource_exists(ref)
+    time.sleep(CREATE_WAIT_AFTER_SECONDS)
+
+    yield (ref, cr)
+
+    if k8s.get_resource_exists(ref):
+        _, deleted = k8s.delete_custom_resource(ref)
+        assert deleted
+
+
+@service_marker
+@pytest.mark.canary
+class Test{{ .Resource.Name }}:
+    def test_create(self, {{ .Resource.Snake }}):
+        (ref, _) = {{ .Resource.Snake }}
+        wait_for_synced(ref)
+
+        cr = k8s.get_resource(ref)
+        assert "status" in cr
+        assert "ackResourceMetadata" in cr["status"]
+
+    def test_read(self, {{ .ServicePackageName }}_client, {{ .Resource.Snake }}):
+        (ref, _) = {{ .Resource.Snake }}
+        wait_for_synced(ref)
+
+        cr = k8s.get_resource(ref)
+        response = describe_{{ .Resource.Snake }}({{ .ServicePackageName }}_client, cr)
+        assert response is not None
+        # TODO: assert the described resource matches the spec
+
+    def test_update(self, {{ .Resource.Snake }}):
+        (ref, _) = {{ .Resource.Snake }}
+        wait_for_synced(ref)
+
+        # TODO: set the spec fields to update
+        updates = {
+            "spec": {},
+        }
+        k8s.patch_custom_resource(ref, updates)
+        time.sleep(MODIFY_WAIT_AFTER_SECONDS)
+        wait_for_synced(ref)
+
+    def test_delete(self, {{ .ServicePackageName }}_client, {{ .Resource.Snake }}):
+        (ref, _) = {{ .Resource.Snake }}
+        wait_for_synced(ref)
+        cr = k8s.get_resource(ref)
+
+        _, deleted = k8s.delete_custom_resource(ref)
+        assert deleted
+        time.sleep(DELETE_WAIT_AFTER_SECONDS)
+
+        assert not k8s.get_resource_exists(ref)
+{{- $read := .Resource.Read }}
+{{- if and $read $read.List $read.Filtered $read.Result }}
+        # {{ $read.Name }} returns no {{ .Resource.Plural }} instead of an error
+        response = describe_{{ .Resource.Snake }}({{ .ServicePackageName }}_client, cr)
+        assert not response["{{ $read.Result }}"]
+{{- else if and $read $read.List }}
+        # TODO: {{ $read.Name }} also lists the other {{ .Resource.Plural }} of the
+        # account, assert that the response does not list the deleted one
+        response = describe_{{ .Resource.Snake }}({{ .ServicePackageName }}_client, cr)
+        assert response is not None
+{{- else }}
+        with pytest.raises(ClientError):
+            describe_{{ .Resource.Snake }}({{ .ServicePackageName }}_client, cr)
+{{- end }}